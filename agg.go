@@ -0,0 +1,205 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// aggOptions selects what aggregate computes over the Broker history.
+type aggOptions struct {
+	By       string        // property to group by; "" for none
+	Interval time.Duration // time bucket width; 0 for no buckets
+	Buckets  int           // with Interval 0: pick a width giving about this many buckets
+	Where    *query        // nil matches every entry
+	Top      int           // number of By values reported
+	Stats    []string      // numeric properties to summarise
+}
+
+type valueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type aggBucket struct {
+	Start  time.Time      `json:"start"`
+	Count  int            `json:"count"`
+	Levels map[string]int `json:"levels"`
+	Groups map[string]int `json:"groups,omitempty"` // counts of the top By values
+}
+
+type numStats struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+}
+
+type aggResult struct {
+	Total      int                  `json:"total"`
+	IntervalMs int64                `json:"intervalMs,omitempty"`
+	Levels     map[string]int       `json:"levels"`
+	Top        []valueCount         `json:"top,omitempty"`
+	Buckets    []aggBucket          `json:"buckets,omitempty"`
+	Stats      map[string]*numStats `json:"stats,omitempty"`
+}
+
+// aggregate counts msgs by level, By value and time bucket, and summarises
+// the Stats properties. Entries without a timestamp are left out of buckets.
+func aggregate(msgs []logMsg, opt aggOptions) aggResult {
+	res := aggResult{Levels: map[string]int{}}
+	groups := map[string]int{}
+	values := map[string][]float64{}
+	type timed struct {
+		ts    time.Time
+		level string
+		group string
+		n     int
+	}
+	var times []timed
+
+	for _, m := range msgs {
+		e := parseEntry(m.S, m.D)
+		if !opt.Where.match(e) {
+			continue
+		}
+		n := max(m.N, 1) // folded repeats count once per occurrence
+		res.Total += n
+		if e.Level != "" {
+			res.Levels[e.Level] += n
+		}
+		var group string
+		if opt.By != "" {
+			if v, ok := e.field(opt.By); ok {
+				group = fieldString(v)
+			}
+			groups[group] += n
+		}
+		for _, prop := range opt.Stats {
+			if v, ok := e.field(prop); ok {
+				if f, ok := fieldNumber(v); ok {
+					values[prop] = append(values[prop], f)
+				}
+			}
+		}
+		if (opt.Interval > 0 || opt.Buckets > 0) && !e.Time.IsZero() {
+			times = append(times, timed{e.Time, e.Level, group, n})
+		}
+	}
+
+	if opt.By != "" {
+		res.Top = topValues(groups, opt.Top)
+	}
+	if len(values) > 0 {
+		res.Stats = map[string]*numStats{}
+		for prop, vs := range values {
+			res.Stats[prop] = summarise(vs)
+		}
+	}
+	if opt.Interval == 0 && len(times) > 0 {
+		lo, hi := times[0].ts, times[0].ts
+		for _, t := range times {
+			if t.ts.Before(lo) {
+				lo = t.ts
+			}
+			if t.ts.After(hi) {
+				hi = t.ts
+			}
+		}
+		opt.Interval = niceInterval(hi.Sub(lo) / time.Duration(opt.Buckets))
+	}
+	if opt.Interval > 0 && len(times) > 0 {
+		res.IntervalMs = opt.Interval.Milliseconds()
+		inTop := map[string]bool{}
+		for _, vc := range res.Top {
+			inTop[vc.Value] = true
+		}
+		index := map[int64]int{}
+		for _, t := range times {
+			start := t.ts.Truncate(opt.Interval)
+			i, ok := index[start.UnixNano()]
+			if !ok {
+				i = len(res.Buckets)
+				index[start.UnixNano()] = i
+				res.Buckets = append(res.Buckets, aggBucket{Start: start, Levels: map[string]int{}})
+			}
+			bk := &res.Buckets[i]
+			bk.Count += t.n
+			if t.level != "" {
+				bk.Levels[t.level] += t.n
+			}
+			if inTop[t.group] {
+				if bk.Groups == nil {
+					bk.Groups = map[string]int{}
+				}
+				bk.Groups[t.group] += t.n
+			}
+		}
+		sort.Slice(res.Buckets, func(i, j int) bool {
+			return res.Buckets[i].Start.Before(res.Buckets[j].Start)
+		})
+	}
+	return res
+}
+
+// bucketSteps are the bucket widths niceInterval rounds up to.
+var bucketSteps = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// niceInterval rounds d up to a human-friendly bucket width of at least 1s.
+func niceInterval(d time.Duration) time.Duration {
+	for _, step := range bucketSteps {
+		if d <= step {
+			return step
+		}
+	}
+	day := 24 * time.Hour
+	return (d + day - 1) / day * day
+}
+
+// topValues returns the n most frequent values, ties broken alphabetically.
+func topValues(counts map[string]int, n int) []valueCount {
+	out := make([]valueCount, 0, len(counts))
+	for v, c := range counts {
+		out = append(out, valueCount{v, c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func summarise(vs []float64) *numStats {
+	sort.Float64s(vs)
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return &numStats{
+		Count: len(vs),
+		Min:   vs[0],
+		Max:   vs[len(vs)-1],
+		Avg:   sum / float64(len(vs)),
+		P50:   percentile(vs, 0.50),
+		P95:   percentile(vs, 0.95),
+		P99:   percentile(vs, 0.99),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNiceInterval(t *testing.T) {
+	assert.Equal(t, time.Second, niceInterval(0))
+	assert.Equal(t, 5*time.Second, niceInterval(3*time.Second))
+	assert.Equal(t, time.Minute, niceInterval(45*time.Second))
+	assert.Equal(t, 24*time.Hour, niceInterval(13*time.Hour))
+	assert.Equal(t, 72*time.Hour, niceInterval(50*time.Hour))
+}
+
+func TestAggregate(t *testing.T) {
+	var msgs []logMsg
+	for i := range 100 {
+		level, svc := "info", "api"
+		if i%4 == 0 {
+			level, svc = "error", "worker"
+		}
+		msgs = append(msgs, logMsg{S: "app.log", D: fmt.Sprintf(
+			`{"time":"2024-01-15T03:%02d:%02dZ","level":%q,"service":%q,"duration_ms":%d}`,
+			i/60, i%60, level, svc, i+1)})
+	}
+	msgs = append(msgs, logMsg{S: "app.log", D: "plain"})
+
+	t.Run("levels, top values and stats", func(t *testing.T) {
+		res := aggregate(msgs, aggOptions{By: "service", Top: 10, Stats: []string{"duration_ms"}})
+		assert.Equal(t, 101, res.Total)
+		assert.Equal(t, 75, res.Levels["INFO"])
+		assert.Equal(t, 25, res.Levels["ERROR"])
+		assert.Equal(t, []valueCount{{"api", 75}, {"worker", 25}, {"", 1}}, res.Top)
+
+		st := res.Stats["duration_ms"]
+		require.NotNil(t, st)
+		assert.Equal(t, 100, st.Count)
+		assert.Equal(t, 1.0, st.Min)
+		assert.Equal(t, 100.0, st.Max)
+		assert.Equal(t, 50.5, st.Avg)
+		assert.Equal(t, 50.0, st.P50)
+		assert.Equal(t, 95.0, st.P95)
+		assert.Equal(t, 99.0, st.P99)
+	})
+
+	t.Run("time buckets", func(t *testing.T) {
+		res := aggregate(msgs, aggOptions{By: "service", Top: 1, Interval: time.Minute})
+		require.Len(t, res.Buckets, 2)
+		assert.Equal(t, 60, res.Buckets[0].Count)
+		assert.Equal(t, 40, res.Buckets[1].Count)
+		assert.Equal(t, 15, res.Buckets[0].Levels["ERROR"])
+		assert.Equal(t, map[string]int{"api": 45}, res.Buckets[0].Groups)
+		assert.True(t, res.Buckets[0].Start.Before(res.Buckets[1].Start))
+	})
+
+	t.Run("automatic bucket width", func(t *testing.T) {
+		res := aggregate(msgs, aggOptions{Buckets: 10})
+		assert.Equal(t, int64(10_000), res.IntervalMs)
+		require.Len(t, res.Buckets, 10)
+		assert.Equal(t, 10, res.Buckets[0].Count)
+	})
+
+	t.Run("where filter", func(t *testing.T) {
+		where, err := parseQuery("level == ERROR")
+		require.NoError(t, err)
+		res := aggregate(msgs, aggOptions{Where: where})
+		assert.Equal(t, 25, res.Total)
+		assert.Nil(t, res.Stats)
+	})
+}
+
+func TestAggregateCountsFoldedRepeats(t *testing.T) {
+	res := aggregate([]logMsg{{S: "a.log", D: `{"level":"error","msg":"retry"}`, N: 312}}, aggOptions{})
+	assert.Equal(t, 312, res.Total)
+	assert.Equal(t, 312, res.Levels["ERROR"])
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// The HTTP server only answers requests carrying the per-launch token: the
+// window and the browser get it once in the launch URL and keep it in a
+// cookie, the UI sends it in tokenHeader with every fetch, scripts send the
+// header themselves.
+//
+// The cookie alone is enough only for GET, which EventSource and downloads
+// need. Anything that changes state needs the header, which a foreign page
+// cannot set without a CORS preflight that the server never answers.
+const (
+	tokenHeader = "X-Jsonlv-Token"
+	tokenCookie = "jsonlv_token"
+)
+
+// tokenEnv fixes the token, e.g. for end-to-end tests. It is read from the
+// environment rather than a flag so that it does not show up in ps.
+const tokenEnv = "JSONLV_TOKEN"
+
+// launchToken returns the token from tokenEnv, or a random one, and removes
+// tokenEnv so that editors and other child processes do not inherit it.
+func launchToken() string {
+	token := os.Getenv(tokenEnv)
+	os.Unsetenv(tokenEnv) //nolint:errcheck
+	if token == "" {
+		token = newToken()
+	}
+	return token
+}
+
+// newToken returns a random token for one launch.
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// serverAuth guards the HTTP server listening on 127.0.0.1:port.
+type serverAuth struct {
+	token string
+	port  int
+}
+
+// launchURL opens the UI; its token is moved into the cookie on first load.
+func (a serverAuth) launchURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d/?token=%s", a.port, a.token)
+}
+
+func (a serverAuth) hosts() []string {
+	return []string{fmt.Sprintf("127.0.0.1:%d", a.port), fmt.Sprintf("localhost:%d", a.port)}
+}
+
+func (a serverAuth) origins() []string {
+	return []string{fmt.Sprintf("http://127.0.0.1:%d", a.port), fmt.Sprintf("http://localhost:%d", a.port)}
+}
+
+func (a serverAuth) valid(token string) bool {
+	return a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+// wrap rejects requests for another host name (DNS rebinding), from another
+// origin, or without the token, and handles the launch URL.
+func (a serverAuth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(a.hosts(), r.Host) {
+			http.Error(rw, "forbidden host", http.StatusForbidden)
+			return
+		}
+		if o := r.Header.Get("Origin"); o != "" && !slices.Contains(a.origins(), o) || !sameOrigin(r) {
+			http.Error(rw, "forbidden origin", http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodGet && r.URL.Path == "/" && r.URL.Query().Has("token") {
+			if !a.valid(r.URL.Query().Get("token")) {
+				http.Error(rw, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(rw, &http.Cookie{
+				Name:     tokenCookie,
+				Value:    a.token,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+			// Keep the token out of the address bar and history.
+			http.Redirect(rw, r, "/", http.StatusSeeOther)
+			return
+		}
+		if a.valid(r.Header.Get(tokenHeader)) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		if c, err := r.Cookie(tokenCookie); err == nil && a.valid(c.Value) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(rw, r)
+				return
+			}
+			http.Error(rw, "missing "+tokenHeader+" header", http.StatusForbidden)
+			return
+		}
+		http.Error(rw, "unauthorized: open the URL jsonlv printed at startup", http.StatusUnauthorized)
+	})
+}
+
+// sameOrigin reports whether the browser says the request comes from the UI
+// itself or from the user (address bar, launch), not from another site.
+// Other ports of localhost count as "same-site", so only same-origin passes.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+		return true
+	}
+	return false
+}
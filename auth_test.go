@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerAuth(t *testing.T) {
+	a := serverAuth{token: "secret", port: 4321}
+	ok := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	h := a.wrap(ok)
+	do := func(method, target string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.Host = "127.0.0.1:4321"
+		for k, v := range header {
+			if k == "Host" {
+				r.Host = v
+			} else {
+				r.Header.Set(k, v)
+			}
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		return rw
+	}
+	cookie := map[string]string{"Cookie": tokenCookie + "=secret"}
+
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/events", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/", map[string]string{"Cookie": tokenCookie + "=wrong"}).Code)
+	assert.Equal(t, http.StatusNoContent, do("GET", "/events", cookie).Code)
+	assert.Equal(t, http.StatusNoContent, do("POST", "/set-theme", map[string]string{tokenHeader: "secret"}).Code)
+	assert.Equal(t, http.StatusNoContent, do("GET", "/agg", map[string]string{tokenHeader: "secret", "Host": "localhost:4321"}).Code)
+
+	// The cookie is sent along by any page that makes the browser post here.
+	assert.Equal(t, http.StatusForbidden, do("POST", "/set-theme", cookie).Code)
+
+	for name, header := range map[string]map[string]string{
+		"rebound host":   {tokenHeader: "secret", "Host": "evil.example:4321"},
+		"other port":     {tokenHeader: "secret", "Host": "127.0.0.1:80"},
+		"foreign origin": {tokenHeader: "secret", "Origin": "https://evil.example"},
+		"other app":      {tokenHeader: "secret", "Origin": "http://localhost:3000"},
+		"cross-site":     {"Cookie": tokenCookie + "=secret", "Sec-Fetch-Site": "cross-site"},
+		"same-site":      {"Cookie": tokenCookie + "=secret", "Sec-Fetch-Site": "same-site"},
+	} {
+		assert.Equal(t, http.StatusForbidden, do("GET", "/events", header).Code, name)
+	}
+	assert.Equal(t, http.StatusNoContent, do("POST", "/bookmarks", map[string]string{
+		tokenHeader: "secret", "Origin": "http://127.0.0.1:4321", "Sec-Fetch-Site": "same-origin",
+	}).Code)
+
+	// The launch URL trades the token for the cookie and drops it from the URL.
+	rw := do("GET", strings.TrimPrefix(a.launchURL(), "http://127.0.0.1:4321"), map[string]string{"Sec-Fetch-Site": "none"})
+	assert.Equal(t, http.StatusSeeOther, rw.Code)
+	assert.Equal(t, "/", rw.Header().Get("Location"))
+	cookies := rw.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "secret", cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/?token=guess", nil).Code)
+
+	// Without a token nothing is valid, not even an empty one.
+	open := serverAuth{port: 4321}.wrap(ok)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Host = "127.0.0.1:4321"
+	r.Header.Set(tokenHeader, "")
+	rw = httptest.NewRecorder()
+	open.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+}
+
+func TestNewToken(t *testing.T) {
+	a, b := newToken(), newToken()
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, b)
+}
+
+func TestLaunchTokenFromEnv(t *testing.T) {
+	t.Setenv(tokenEnv, "e2e-token")
+	assert.Equal(t, "e2e-token", launchToken())
+	_, set := os.LookupEnv(tokenEnv)
+	assert.False(t, set, "child processes must not inherit the token")
+	assert.Len(t, launchToken(), 64)
+}
+
+func TestServeHTMLCarriesToken(t *testing.T) {
+	page := serveHTML(`a"b`)
+	assert.Contains(t, page, `<meta name="jsonlv-token" content="a&#34;b">`)
+}
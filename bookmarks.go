@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// bookmark marks one Log Entry, optionally with a note. It is identified by
+// its source, the line's byte offset in the file when known, and a hash of the
+// line, so it survives restarts but not the line being rewritten.
+type bookmark struct {
+	Source  string    `json:"source"`
+	Offset  *int64    `json:"offset,omitempty"`
+	Hash    string    `json:"hash"`
+	Line    string    `json:"line"` // kept to show bookmarks whose entry is not loaded
+	Note    string    `json:"note,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+var (
+	bookmarkMu sync.Mutex
+	bookmarks  []bookmark
+)
+
+func lineHash(line string) string {
+	h := fnv.New64a()
+	h.Write([]byte(line)) //nolint:errcheck
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func bookmarkKey(source string, offset *int64, hash string) string {
+	if offset == nil {
+		return source + "\x00-\x00" + hash
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s", source, *offset, hash)
+}
+
+func (bm bookmark) key() string { return bookmarkKey(bm.Source, bm.Offset, bm.Hash) }
+
+func bookmarksFile() string {
+	return filepath.Join(configDir(), "bookmarks.json")
+}
+
+func initBookmarks() {
+	data, err := os.ReadFile(bookmarksFile())
+	if err != nil {
+		return
+	}
+	var bms []bookmark
+	if json.Unmarshal(data, &bms) == nil {
+		bookmarkMu.Lock()
+		bookmarks = bms
+		bookmarkMu.Unlock()
+	}
+}
+
+func saveBookmarks() {
+	f := bookmarksFile()
+	if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+		return
+	}
+	bookmarkMu.Lock()
+	data, _ := json.MarshalIndent(bookmarks, "", "  ")
+	bookmarkMu.Unlock()
+	os.WriteFile(f, data, 0o644) //nolint:errcheck
+}
+
+func listBookmarks() []bookmark {
+	bookmarkMu.Lock()
+	defer bookmarkMu.Unlock()
+	return append([]bookmark(nil), bookmarks...)
+}
+
+// setBookmark adds bm, or updates the note of the bookmark on the same line,
+// and persists the list.
+func setBookmark(bm bookmark) bookmark {
+	if bm.Hash == "" {
+		bm.Hash = lineHash(bm.Line)
+	}
+	if bm.Created.IsZero() {
+		bm.Created = time.Now()
+	}
+	bookmarkMu.Lock()
+	found := false
+	for i := range bookmarks {
+		if bookmarks[i].key() == bm.key() {
+			bookmarks[i].Note = bm.Note
+			bm = bookmarks[i]
+			found = true
+			break
+		}
+	}
+	if !found {
+		bookmarks = append(bookmarks, bm)
+	}
+	bookmarkMu.Unlock()
+	saveBookmarks()
+	return bm
+}
+
+// removeBookmark deletes the bookmark on line and reports whether one existed.
+func removeBookmark(source string, offset *int64, line string) bool {
+	key := bookmarkKey(source, offset, lineHash(line))
+	bookmarkMu.Lock()
+	n := len(bookmarks)
+	for i := range bookmarks {
+		if bookmarks[i].key() == key {
+			bookmarks = append(bookmarks[:i], bookmarks[i+1:]...)
+			break
+		}
+	}
+	removed := len(bookmarks) < n
+	bookmarkMu.Unlock()
+	if removed {
+		saveBookmarks()
+	}
+	return removed
+}
+
+// bookmarkNotes returns the non-empty notes keyed like bookmark.key, for
+// annotating exports.
+func bookmarkNotes() map[string]string {
+	bookmarkMu.Lock()
+	defer bookmarkMu.Unlock()
+	notes := map[string]string{}
+	for _, bm := range bookmarks {
+		if bm.Note != "" {
+			notes[bm.key()] = bm.Note
+		}
+	}
+	return notes
+}
+
+// msgNote returns the note attached to msg in notes, if any.
+func msgNote(notes map[string]string, msg logMsg) string {
+	return notes[bookmarkKey(msg.S, msg.O, lineHash(msg.D))]
+}
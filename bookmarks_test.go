@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetBookmarks(t *testing.T) {
+	t.Helper()
+	configDirOverride = t.TempDir()
+	bookmarkMu.Lock()
+	bookmarks = nil
+	bookmarkMu.Unlock()
+	t.Cleanup(func() {
+		configDirOverride = ""
+		bookmarkMu.Lock()
+		bookmarks = nil
+		bookmarkMu.Unlock()
+	})
+}
+
+func TestBookmarksPersistAndUpdateNote(t *testing.T) {
+	resetBookmarks(t)
+	off := int64(120)
+	setBookmark(bookmark{Source: "app.log", Offset: &off, Line: `{"msg":"boom"}`})
+	bm := setBookmark(bookmark{Source: "app.log", Offset: &off, Line: `{"msg":"boom"}`, Note: "deploy 14:02"})
+	assert.Equal(t, lineHash(`{"msg":"boom"}`), bm.Hash)
+	assert.False(t, bm.Created.IsZero())
+
+	bookmarkMu.Lock()
+	bookmarks = nil
+	bookmarkMu.Unlock()
+	initBookmarks()
+
+	got := listBookmarks()
+	require.Len(t, got, 1)
+	assert.Equal(t, "deploy 14:02", got[0].Note)
+	assert.Equal(t, int64(120), *got[0].Offset)
+}
+
+func TestBookmarksDistinguishOffsets(t *testing.T) {
+	resetBookmarks(t)
+	a, b := int64(0), int64(10)
+	setBookmark(bookmark{Source: "app.log", Offset: &a, Line: "retry", Note: "first"})
+	setBookmark(bookmark{Source: "app.log", Offset: &b, Line: "retry"})
+	assert.Len(t, listBookmarks(), 2)
+
+	notes := bookmarkNotes()
+	assert.Equal(t, "first", msgNote(notes, logMsg{S: "app.log", D: "retry", O: &a}))
+	assert.Empty(t, msgNote(notes, logMsg{S: "app.log", D: "retry", O: &b}))
+	assert.Empty(t, msgNote(notes, logMsg{S: "app.log", D: "retry!", O: &a}), "a changed line no longer matches")
+
+	assert.True(t, removeBookmark("app.log", &a, "retry"))
+	assert.False(t, removeBookmark("app.log", &a, "retry"))
+	assert.Len(t, listBookmarks(), 1)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBrokerPublishDeliveredToSubscriber(t *testing.T) {
@@ -34,9 +35,11 @@ func TestBrokerPublishBatchDeliveredInOrder(t *testing.T) {
 		{S: "a.log", D: "line3"},
 	}
 	b.publishBatch(msgs)
-	assert.Equal(t, logMsg{S: "a.log", D: "line1"}, <-ch)
-	assert.Equal(t, logMsg{S: "b.log", D: "line2"}, <-ch)
-	assert.Equal(t, logMsg{S: "a.log", D: "line3"}, <-ch)
+	for _, want := range msgs {
+		got := <-ch
+		assert.Equal(t, [2]string{want.S, want.D}, [2]string{got.S, got.D})
+		assert.NotZero(t, got.M, "every entry is filed under a pattern")
+	}
 }
 
 func TestBrokerPublishBatchAppearsInHistory(t *testing.T) {
@@ -62,3 +65,75 @@ func TestBrokerUnsubscribeStopsDelivery(t *testing.T) {
 	b.publish("a.log", "line")
 	assert.Zero(t, len(ch))
 }
+
+func TestBrokerDedupFoldsConsecutiveRepeatsPerSource(t *testing.T) {
+	b := newBroker()
+	b.setDedup(true)
+	_, ch := b.subscribe()
+
+	b.publish("a.log", `{"time":"2024-01-15T10:00:00Z","msg":"retry"}`)
+	b.publish("b.log", `{"msg":"other source"}`)
+	b.publish("a.log", `{"time":"2024-01-15T10:00:01Z","msg":"retry"}`)
+	b.publish("a.log", `{"time":"2024-01-15T10:00:02Z","msg":"retry"}`)
+
+	hist, _ := b.subscribe()
+	require.Len(t, hist, 2)
+	assert.Equal(t, 3, hist[0].N)
+	assert.Equal(t, "2024-01-15T10:00:00Z", hist[0].F)
+	assert.Equal(t, "2024-01-15T10:00:02Z", hist[0].L)
+	assert.False(t, hist[0].U)
+
+	<-ch
+	<-ch
+	upd := <-ch
+	assert.True(t, upd.U)
+	assert.Equal(t, 2, upd.N)
+	assert.Equal(t, "a.log", upd.S)
+	assert.Equal(t, 3, (<-ch).N)
+}
+
+func TestBrokerDedupKeepsDistinctEntries(t *testing.T) {
+	b := newBroker()
+	b.setDedup(true)
+	b.publish("a.log", `{"msg":"one"}`)
+	b.publish("a.log", `{"msg":"two"}`)
+	b.publish("a.log", `{"msg":"one"}`)
+	hist, _ := b.subscribe()
+	assert.Len(t, hist, 3)
+}
+
+func TestBrokerDedupOffByDefault(t *testing.T) {
+	b := newBroker()
+	b.publish("a.log", "same")
+	b.publish("a.log", "same")
+	hist, _ := b.subscribe()
+	assert.Len(t, hist, 2)
+	assert.Zero(t, hist[0].N)
+}
+
+func TestBrokerDedupAcrossBatch(t *testing.T) {
+	b := newBroker()
+	b.setDedup(true)
+	b.publishBatch([]logMsg{{S: "a.log", D: "x"}, {S: "a.log", D: "x"}, {S: "a.log", D: "y"}})
+	hist, _ := b.subscribe()
+	require.Len(t, hist, 2)
+	assert.Equal(t, 2, hist[0].N)
+}
+
+func TestBrokerRemoveFile(t *testing.T) {
+	b := newBroker()
+	b.setDedup(true)
+	b.publishAt("/a/a.log", "a.log", "line1", 0)
+	b.publish("b.log", "line2")
+	b.publishAt("/other/a.log", "a.log", "line3", 0)
+	b.publishAt("/a/a.log", "a.log", "line4", 6)
+	b.removeFile("/a/a.log")
+	hist := b.snapshot()
+	require.Len(t, hist, 2)
+	assert.Equal(t, []string{"line2", "line3"}, []string{hist[0].D, hist[1].D})
+	assert.Equal(t, "/other/a.log", hist[1].P, "same basename, other file")
+
+	// Folding starts over instead of pointing at a moved entry.
+	b.publish("b.log", "line2")
+	assert.Len(t, b.snapshot(), 3)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fsEntry is one item of a directory listing for the -browser file dialog,
+// which stands in for the native file pickers.
+type fsEntry struct {
+	Name    string `json:"name"`
+	Dir     bool   `json:"dir,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	ModTime int64  `json:"mtime,omitempty"` // unix milliseconds
+}
+
+type fsListing struct {
+	Path    string    `json:"path"`
+	Parent  string    `json:"parent,omitempty"` // "" at the root
+	Entries []fsEntry `json:"entries"`
+}
+
+// defaultBrowseDir is where the file dialog starts: the directory of the most
+// recent file, else the home directory.
+func defaultBrowseDir(recent []string) string {
+	for _, p := range recent {
+		if fi, err := os.Stat(filepath.Dir(p)); err == nil && fi.IsDir() {
+			return filepath.Dir(p)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return "/"
+}
+
+// listDir lists the directory path, directories first, then by name. Hidden
+// entries are left out unless hidden is set. A file lists its directory.
+func listDir(path string, hidden bool) (fsListing, error) {
+	if !filepath.IsAbs(path) {
+		return fsListing{}, errors.New("path must be absolute")
+	}
+	path = filepath.Clean(path)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fsListing{}, err
+	}
+	if !fi.IsDir() {
+		path = filepath.Dir(path)
+	}
+	des, err := os.ReadDir(path)
+	if err != nil {
+		return fsListing{}, err
+	}
+	l := fsListing{Path: path, Entries: []fsEntry{}}
+	if parent := filepath.Dir(path); parent != path {
+		l.Parent = parent
+	}
+	for _, de := range des {
+		if !hidden && strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+		// Stat follows symlinks, so a link to a directory can be entered.
+		info, err := os.Stat(filepath.Join(path, de.Name()))
+		if err != nil {
+			continue
+		}
+		e := fsEntry{Name: de.Name(), Dir: info.IsDir(), ModTime: info.ModTime().UnixMilli()}
+		if !e.Dir {
+			e.Size = info.Size()
+		}
+		l.Entries = append(l.Entries, e)
+	}
+	sort.Slice(l.Entries, func(i, j int) bool {
+		a, b := l.Entries[i], l.Entries[j]
+		if a.Dir != b.Dir {
+			return a.Dir
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return l, nil
+}
+
+// browserReloadEnv is set when a -browser process replaces itself to restart
+// or switch workspaces. The page already open reloads itself, so the new
+// process does not open another one.
+const browserReloadEnv = "JSONLV_BROWSER_RELOAD"
+
+// browserCommand adapts a command line and environment for execSelf so the
+// open page keeps working: the same port and token, and no new page.
+func browserCommand(args, env []string, port int, token string) ([]string, []string) {
+	args = append([]string{args[0], fmt.Sprintf("-port=%d", port)}, args[1:]...)
+	return args, append(env, tokenEnv+"="+token, browserReloadEnv+"=1")
+}
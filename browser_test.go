@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "logs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.log"), []byte("12345"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "A.log"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "logs"), filepath.Join(dir, "link")))
+
+	l, err := listDir(dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, dir, l.Path)
+	assert.Equal(t, filepath.Dir(dir), l.Parent)
+	var names []string
+	for _, e := range l.Entries {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"link", "logs", "A.log", "b.log"}, names)
+	assert.True(t, l.Entries[0].Dir, "symlinks to directories can be entered")
+	assert.Equal(t, int64(5), l.Entries[3].Size)
+
+	l, err = listDir(dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, ".hidden", l.Entries[2].Name)
+
+	// A file lists its directory.
+	l, err = listDir(filepath.Join(dir, "b.log"), false)
+	require.NoError(t, err)
+	assert.Equal(t, dir, l.Path)
+
+	l, err = listDir("/", false)
+	require.NoError(t, err)
+	assert.Empty(t, l.Parent)
+
+	_, err = listDir("relative/path", false)
+	assert.Error(t, err)
+	_, err = listDir(filepath.Join(dir, "missing"), false)
+	assert.Error(t, err)
+}
+
+func TestDefaultBrowseDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, dir, defaultBrowseDir([]string{"/does/not/exist/a.log", filepath.Join(dir, "app.log")}))
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, home, defaultBrowseDir(nil))
+}
+
+func TestBrowserCommandKeepsPortAndToken(t *testing.T) {
+	args, env := browserCommand([]string{"jsonlv", "-browser", "app.log"}, []string{"HOME=/home/me"}, 4321, "secret")
+	assert.Equal(t, []string{"jsonlv", "-port=4321", "-browser", "app.log"}, args)
+	assert.Equal(t, []string{"HOME=/home/me", "JSONLV_TOKEN=secret", "JSONLV_BROWSER_RELOAD=1"}, env)
+}
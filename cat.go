@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// catUsage is printed for `jsonlv cat -h`.
+const catUsage = `usage: jsonlv cat [flags] [file ...]
+
+Prints Log Entries from the files, or stdin, without a UI: filtered and
+formatted like the viewer's rows, or re-emitted as JSONL with -json.
+
+`
+
+// catFilter selects the Log Entries `jsonlv cat` prints, with the semantics of
+// the viewer's level buttons, property filters and /export's where.
+type catFilter struct {
+	levels map[string]bool            // empty: all levels
+	props  map[string]map[string]bool // prop → accepted values
+	where  *query
+}
+
+func (f catFilter) match(e logEntry) bool {
+	if len(f.levels) > 0 && !f.levels[e.Level] {
+		return false
+	}
+	for prop, vals := range f.props {
+		if !vals[propValue(e, prop)] {
+			return false
+		}
+	}
+	return f.where.match(e)
+}
+
+// runCat implements `jsonlv cat` and returns the exit code.
+func runCat(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, catUsage)
+		fs.PrintDefaults()
+	}
+	follow := fs.Bool("f", false, "follow the files for new lines")
+	lines := fs.Int("n", 0, "start with the last n lines of each file (0 = whole file)")
+	sinceArg := fs.String("since", "", "only lines at or after this time")
+	untilArg := fs.String("until", "", "only lines up to this time")
+	levelArg := fs.String("level", "", "comma-separated levels to print, e.g. ERROR,CRITICAL")
+	whereArg := fs.String("where", "", `query the entries must match, e.g. "status_code >= 500"`)
+	colsArg := fs.String("cols", "", "comma-separated properties to print as columns")
+	asJSON := fs.Bool("json", false, "print the matching lines unchanged as JSONL")
+	colorArg := fs.String("color", "auto", "colour output: auto, always or never")
+	filter := catFilter{levels: map[string]bool{}, props: map[string]map[string]bool{}}
+	fs.Func("p", "property filter prop=value; repeat to accept several values", func(s string) error {
+		prop, val, ok := strings.Cut(s, "=")
+		if !ok || prop == "" {
+			return errors.New("want prop=value")
+		}
+		if filter.props[prop] == nil {
+			filter.props[prop] = map[string]bool{}
+		}
+		filter.props[prop][val] = true
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	since, err := parseTimeArg(*sinceArg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: -since: %v\n", err)
+		return 2
+	}
+	until, err := parseTimeArg(*untilArg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: -until: %v\n", err)
+		return 2
+	}
+	if filter.where, err = parseQuery(*whereArg); err != nil {
+		fmt.Fprintf(stderr, "error: -where: %v\n", err)
+		return 2
+	}
+	for _, l := range strings.Split(*levelArg, ",") {
+		if l = normalizeLevel(strings.TrimSpace(l)); l != "" && l != "ALL" {
+			filter.levels[l] = true
+		}
+	}
+	var cols []string
+	for _, c := range strings.Split(*colsArg, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	var color bool
+	switch *colorArg {
+	case "always":
+		color = true
+	case "never":
+	case "auto":
+		color = isTerminal(stdout)
+	default:
+		fmt.Fprintf(stderr, "error: -color: want auto, always or never\n")
+		return 2
+	}
+
+	// Lines are masked by redactMsg only; nothing here can reveal originals.
+	redaction := loadPrefs().redaction()
+	redaction.KeepOriginals = false
+	if err := setRedaction(redaction); err != nil {
+		fmt.Fprintf(stderr, "error: redaction: %v\n", err)
+		return 2
+	}
+
+	files := fs.Args()
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	emit := func(msg logMsg) {
+		e := parseEntry(msg.S, msg.D)
+		if !filter.match(e) {
+			return
+		}
+		if *asJSON {
+			out.WriteString(msg.D + "\n") //nolint:errcheck
+			return
+		}
+		if len(files) < 2 {
+			e.Source = "" // one file needs no Source column
+		}
+		out.WriteString(formatEntry(e, 1, cols, color) + "\n") //nolint:errcheck
+	}
+
+	if len(files) == 0 {
+		scanner := bufio.NewScanner(stdin)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				emit(redactMsg(logMsg{D: line}))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stderr, "error: stdin: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	code := 0
+	ends := make([]int64, len(files))
+	for i, path := range files {
+		source := filepath.Base(path)
+		end, err := catFile(path, *lines, since, until, func(line string) {
+			emit(redactMsg(logMsg{S: source, D: line}))
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %s: %v\n", path, err)
+			code = 1
+		}
+		ends[i] = end
+	}
+	if !*follow || !until.IsZero() {
+		return code
+	}
+
+	// Followers block while stdout is slow rather than drop lines.
+	out.Flush() //nolint:errcheck
+	ch := make(chan logMsg, 256)
+	for i, path := range files {
+		source := filepath.Base(path)
+		go followFile(path, ends[i], nil, func(line string, _ int64) {
+			ch <- logMsg{S: source, D: line}
+		})
+	}
+	for msg := range ch {
+		emit(redactMsg(msg))
+		if len(ch) == 0 {
+			out.Flush() //nolint:errcheck
+		}
+	}
+	return code
+}
+
+// catFile calls fn with the non-empty lines of path: those within since and
+// until when either is set, else the last n lines, else all of them. It
+// returns the offset it read up to, where -f continues.
+func catFile(path string, n int, since, until time.Time, fn func(line string)) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	var lines []string
+	switch {
+	case !since.IsZero() || !until.IsZero():
+		lines, _, err = readTimeRangeIn(f, size, since, until, int(^uint(0)>>1))
+	case n > 0:
+		lines, _, err = lastNLinesIn(f, size, n)
+	default:
+		return size, scanLines(f, 0, size, func(_ int64, line string) bool {
+			if line != "" {
+				fn(line)
+			}
+			return true
+		})
+	}
+	for _, line := range lines {
+		if line != "" {
+			fn(line)
+		}
+	}
+	return size, err
+}
+
+// isTerminal reports whether w is a terminal, for -color auto.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const catLog = `{"level":"info","message":"started","service":"api","datetime":"2024-01-15T03:09:00Z"}
+{"level":"error","message":"boom","service":"api","status_code":500,"datetime":"2024-01-15T03:10:00Z"}
+not json
+{"level":"warning","message":"slow","service":"worker","status_code":200,"datetime":"2024-01-15T03:11:00Z"}
+`
+
+func runCatTest(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	t.Cleanup(func() { setRedaction(redactionPrefs{}) }) //nolint:errcheck
+	var out, errOut bytes.Buffer
+	code := runCat(args, strings.NewReader(stdin), &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+func TestCatFilters(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(p, []byte(catLog), 0o644))
+
+	out, _, code := runCatTest(t, "", "-json", "-level", "error,warn", p)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, []string{
+		`{"level":"error","message":"boom","service":"api","status_code":500,"datetime":"2024-01-15T03:10:00Z"}`,
+		`{"level":"warning","message":"slow","service":"worker","status_code":200,"datetime":"2024-01-15T03:11:00Z"}`,
+	}, strings.Split(strings.TrimSpace(out), "\n"))
+
+	out, _, _ = runCatTest(t, "", "-json", "-p", "service=worker", "-p", "service=api", "-where", "status_code >= 500", p)
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+	assert.Contains(t, out, "boom")
+
+	out, _, _ = runCatTest(t, "", "-json", "-n", "2", p)
+	assert.Equal(t, "not json\n"+strings.SplitN(catLog, "\n", 5)[3]+"\n", out)
+
+	out, _, _ = runCatTest(t, "", "-json", "-since", "2024-01-15T03:10:00Z", "-until", "2024-01-15T03:10:30Z", p)
+	assert.Contains(t, out, "boom")
+	assert.NotContains(t, out, "started")
+	assert.NotContains(t, out, "slow")
+}
+
+func TestCatFormatsStdin(t *testing.T) {
+	out, _, code := runCatTest(t, catLog, "-color", "never", "-cols", "service")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasSuffix(lines[1], "ERROR   service=api boom"), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], "not json"), lines[2])
+	assert.NotContains(t, out, "\x1b[")
+
+	out, _, _ = runCatTest(t, catLog, "-color", "always")
+	assert.Contains(t, out, "\x1b[31mERROR")
+}
+
+func TestCatUsageErrors(t *testing.T) {
+	_, errOut, code := runCatTest(t, "", "-where", "a ==")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "-where")
+
+	_, _, code = runCatTest(t, "", "-p", "novalue")
+	assert.Equal(t, 2, code)
+
+	_, errOut, code = runCatTest(t, "", filepath.Join(t.TempDir(), "missing.log"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "missing.log")
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteUsage is printed for `jsonlv remote -h`.
+const remoteUsage = `usage: jsonlv remote <command> [args]
+
+Sends a command to the running jsonlv window:
+
+  open file ...              tail the files
+  close file ...             stop tailing the files and drop their entries
+  filter [-level L] [-p prop=value ...] [-search text]
+                             set the level button, property filters and search
+  clear                      clear the view, like the Clear button
+
+`
+
+var (
+	errNoInstance      = errors.New("no running jsonlv window")
+	errInstanceRunning = errors.New("another jsonlv window owns the control socket")
+)
+
+// controlSocketPath is the unix socket on which the window listens for
+// `jsonlv remote` and for files passed to later invocations. It lives in a
+// directory only the user can enter, so it is never reachable by others, not
+// even before listenControl restricts the socket itself.
+func controlSocketPath() string {
+	return filepath.Join(configDir(), "run", "control.sock")
+}
+
+// controlRequest is the single JSON line a client writes; controlReply is the
+// line it gets back.
+type controlRequest struct {
+	Cmd  string   `json:"cmd"`
+	Args []string `json:"args,omitempty"`
+}
+
+type controlReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// controlActions translates a request into menuFileCh actions: a file path to
+// open, "close:<path>", "filter:<sessionView JSON>" or "clear-view". Paths
+// must be absolute, the client resolves them against its own directory.
+func controlActions(req controlRequest) ([]string, error) {
+	switch req.Cmd {
+	case "open", "close":
+		if len(req.Args) == 0 {
+			return nil, fmt.Errorf("%s: no files", req.Cmd)
+		}
+		actions := make([]string, len(req.Args))
+		for i, p := range req.Args {
+			if !filepath.IsAbs(p) {
+				return nil, fmt.Errorf("%s: %s: path must be absolute", req.Cmd, p)
+			}
+			actions[i] = filepath.Clean(p)
+			if req.Cmd == "close" {
+				actions[i] = "close:" + actions[i]
+			}
+		}
+		return actions, nil
+	case "filter":
+		v, err := parseRemoteFilter(req.Args)
+		if err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+		data, _ := json.Marshal(v)
+		return []string{"filter:" + string(data)}, nil
+	case "clear":
+		if len(req.Args) > 0 {
+			return nil, errors.New("clear: takes no arguments")
+		}
+		return []string{"clear-view"}, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", req.Cmd)
+}
+
+// parseRemoteFilter reads the flags of `jsonlv remote filter` into the part
+// of a sessionView the UI applies, with the flags of `jsonlv cat`.
+func parseRemoteFilter(args []string) (sessionView, error) {
+	var v sessionView
+	fs := flag.NewFlagSet("filter", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	level := fs.String("level", "ALL", "level button: ALL, DEBUG, INFO, WARN, ERROR or CRITICAL")
+	fs.StringVar(&v.Search, "search", "", "text to search for")
+	fs.Func("p", "property filter prop=value; repeat to accept several values", func(s string) error {
+		prop, val, ok := strings.Cut(s, "=")
+		if !ok || prop == "" {
+			return errors.New("want prop=value")
+		}
+		if v.PropertyFilters == nil {
+			v.PropertyFilters = map[string][]string{}
+		}
+		v.PropertyFilters[prop] = append(v.PropertyFilters[prop], val)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return v, err
+	}
+	if fs.NArg() > 0 {
+		return v, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	switch l := strings.ToUpper(*level); l {
+	case "ALL", "DEBUG", "INFO", "WARN", "ERROR", "CRITICAL":
+		v.Level = l
+	default:
+		return v, fmt.Errorf("unknown level %q", *level)
+	}
+	return v, nil
+}
+
+// listenControl claims the control socket for this window. It fails with
+// errInstanceRunning while another window answers on it; a socket left behind
+// by one that died is replaced.
+func listenControl() (net.Listener, error) {
+	path := controlSocketPath()
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return nil, errInstanceRunning
+	}
+	os.Remove(path) //nolint:errcheck
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	// MkdirAll leaves an existing directory's mode alone.
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Only the user may drive the window.
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// serveControl answers requests on ln until it is closed, handing the actions
+// of each valid request to dispatch.
+func serveControl(ln net.Listener, dispatch func(action string)) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer c.Close()
+			c.SetDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+			var req controlRequest
+			var reply controlReply
+			line, err := bufio.NewReader(c).ReadBytes('\n')
+			if err == nil {
+				err = json.Unmarshal(line, &req)
+			}
+			if err != nil {
+				reply.Error = "bad request"
+			} else if actions, err := controlActions(req); err != nil {
+				reply.Error = err.Error()
+			} else {
+				for _, a := range actions {
+					dispatch(a)
+				}
+			}
+			json.NewEncoder(c).Encode(reply) //nolint:errcheck
+		}()
+	}
+}
+
+// sendControl sends req to the running window. It returns errNoInstance when
+// none listens.
+func sendControl(req controlRequest) error {
+	c, err := net.DialTimeout("unix", controlSocketPath(), time.Second)
+	if err != nil {
+		return errNoInstance
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	if err := json.NewEncoder(c).Encode(req); err != nil {
+		return err
+	}
+	var reply controlReply
+	if err := json.NewDecoder(c).Decode(&reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
+// absPaths resolves files against the working directory, which the running
+// window does not share.
+func absPaths(files []string) ([]string, error) {
+	abs := make([]string, len(files))
+	for i, f := range files {
+		p, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		abs[i] = p
+	}
+	return abs, nil
+}
+
+// runRemote implements `jsonlv remote` and returns the exit code.
+func runRemote(args []string, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(stderr, remoteUsage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	req := controlRequest{Cmd: args[0], Args: args[1:]}
+	if req.Cmd == "open" || req.Cmd == "close" {
+		var err error
+		if req.Args, err = absPaths(req.Args); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+	if req.Cmd == "open" {
+		for _, p := range req.Args {
+			if _, err := os.Stat(p); err != nil {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+				return 1
+			}
+		}
+	}
+	// Catch usage errors before looking for the window.
+	if _, err := controlActions(req); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if err := sendControl(req); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
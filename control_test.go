@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlActions(t *testing.T) {
+	actions, err := controlActions(controlRequest{Cmd: "open", Args: []string{"/var/log/a.log", "/var/log/../b.log"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/var/log/a.log", "/var/b.log"}, actions)
+
+	actions, err = controlActions(controlRequest{Cmd: "close", Args: []string{"/var/log/a.log"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"close:/var/log/a.log"}, actions)
+
+	actions, err = controlActions(controlRequest{Cmd: "clear"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"clear-view"}, actions)
+
+	for _, req := range []controlRequest{
+		{Cmd: "open"},
+		{Cmd: "open", Args: []string{"relative.log"}},
+		{Cmd: "clear", Args: []string{"x"}},
+		{Cmd: "filter", Args: []string{"-level", "LOUD"}},
+		{Cmd: "filter", Args: []string{"-p", "novalue"}},
+		{Cmd: "reboot"},
+	} {
+		_, err := controlActions(req)
+		assert.Error(t, err, req)
+	}
+}
+
+func TestControlActionsFilter(t *testing.T) {
+	actions, err := controlActions(controlRequest{Cmd: "filter", Args: []string{
+		"-level", "error", "-p", "service=api", "-p", "service=worker", "-search", "timeout",
+	}})
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	view, ok := bytes.CutPrefix([]byte(actions[0]), []byte("filter:"))
+	require.True(t, ok)
+	var v sessionView
+	require.NoError(t, json.Unmarshal(view, &v))
+	assert.Equal(t, sessionView{
+		Level:           "ERROR",
+		PropertyFilters: map[string][]string{"service": {"api", "worker"}},
+		Search:          "timeout",
+	}, v)
+
+	// No flags resets to all levels.
+	actions, err = controlActions(controlRequest{Cmd: "filter"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`filter:{"level":"ALL"}`}, actions)
+}
+
+func TestControlSocketRoundTrip(t *testing.T) {
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	assert.ErrorIs(t, sendControl(controlRequest{Cmd: "clear"}), errNoInstance)
+
+	// A socket left behind by a window that died does not block the next one.
+	require.NoError(t, os.MkdirAll(filepath.Dir(controlSocketPath()), 0o755))
+	require.NoError(t, os.WriteFile(controlSocketPath(), nil, 0o600))
+	ln, err := listenControl()
+	require.NoError(t, err)
+	defer ln.Close()
+	fi, err := os.Stat(controlSocketPath())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	fi, err = os.Stat(filepath.Dir(controlSocketPath()))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), fi.Mode().Perm(), "the socket's directory is private")
+
+	_, err = listenControl()
+	assert.ErrorIs(t, err, errInstanceRunning)
+
+	got := make(chan string, 10)
+	go serveControl(ln, func(action string) { got <- action })
+
+	require.NoError(t, sendControl(controlRequest{Cmd: "open", Args: []string{"/tmp/a.log", "/tmp/b.log"}}))
+	assert.Equal(t, "/tmp/a.log", <-got)
+	assert.Equal(t, "/tmp/b.log", <-got)
+
+	err = sendControl(controlRequest{Cmd: "open", Args: []string{"a.log"}})
+	assert.EqualError(t, err, "open: a.log: path must be absolute")
+	assert.Empty(t, got)
+}
+
+func TestRunRemote(t *testing.T) {
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	var stderr bytes.Buffer
+	assert.Equal(t, 2, runRemote(nil, &stderr))
+	assert.Contains(t, stderr.String(), "usage: jsonlv remote")
+
+	stderr.Reset()
+	assert.Equal(t, 2, runRemote([]string{"filter", "-level", "LOUD"}, &stderr))
+	stderr.Reset()
+	assert.Equal(t, 1, runRemote([]string{"open", "does-not-exist.log"}, &stderr))
+	stderr.Reset()
+	assert.Equal(t, 1, runRemote([]string{"clear"}, &stderr))
+	assert.Contains(t, stderr.String(), errNoInstance.Error())
+}
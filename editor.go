@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// An editorProfile says how file-path links are opened: either a URL handed
+// to the system's URL opener, or a command run directly. Both are templates
+// where {file}, {line} and {column} are replaced; in URLs they are escaped.
+type editorProfile struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url,omitempty"`     // e.g. "vscode://file{file}:{line}:{column}"
+	Command []string `json:"command,omitempty"` // e.g. ["subl", "{file}:{line}:{column}"]
+}
+
+// defaultEditor is used when prefs name no editor, as before profiles existed.
+const defaultEditor = "phpstorm"
+
+// builtinEditors are offered in Settings; profiles in prefs.json with the same
+// name replace them.
+var builtinEditors = []editorProfile{
+	{Name: "phpstorm", URL: "phpstorm://open?file={file}&line={line}"},
+	{Name: "vscode", URL: "vscode://file{file}:{line}:{column}"},
+	{Name: "goland", Command: []string{"goland", "--line", "{line}", "--column", "{column}", "{file}"}},
+	{Name: "sublime", Command: []string{"subl", "{file}:{line}:{column}"}},
+	{Name: "neovim", Command: []string{"nvim", "--server", "$NVIM_LISTEN_ADDRESS", "--remote", "+{line}", "{file}"}},
+}
+
+// editorProfiles returns the built-in profiles merged with custom ones, in
+// Settings order: built-ins first, then custom profiles by name.
+func editorProfiles(custom []editorProfile) []editorProfile {
+	out := append([]editorProfile(nil), builtinEditors...)
+	for _, p := range custom {
+		if p.Name == "" {
+			continue
+		}
+		replaced := false
+		for i := range out {
+			if out[i].Name == p.Name {
+				out[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// findEditor returns the profile called name, falling back to defaultEditor.
+func findEditor(profiles []editorProfile, name string) editorProfile {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	for _, p := range profiles {
+		if p.Name == defaultEditor {
+			return p
+		}
+	}
+	return builtinEditors[0]
+}
+
+// escapeURLPath escapes s for a URL but keeps slashes, so {file} reads as a
+// path in both "scheme://file{file}" and "?file={file}" templates.
+func escapeURLPath(s string) string {
+	var sb strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// expandEditorTemplate fills the placeholders of tmpl. A missing line or
+// column becomes 1, which every editor accepts.
+func expandEditorTemplate(tmpl, file, line, column string, escape bool) string {
+	if line == "" {
+		line = "1"
+	}
+	if column == "" {
+		column = "1"
+	}
+	if escape {
+		file = escapeURLPath(file)
+	}
+	return strings.NewReplacer("{file}", file, "{line}", line, "{column}", column).Replace(tmpl)
+}
+
+// editorCommand returns the command that opens file at line and column with p.
+// Environment variables in the command are expanded before the placeholders,
+// so a file name cannot smuggle one in.
+func editorCommand(p editorProfile, file, line, column string) ([]string, error) {
+	if p.URL != "" {
+		return openURLCommand(expandEditorTemplate(p.URL, file, line, column, true)), nil
+	}
+	if len(p.Command) == 0 {
+		return nil, errors.New("editor profile " + p.Name + " has neither url nor command")
+	}
+	args := make([]string, len(p.Command))
+	for i, a := range p.Command {
+		args[i] = expandEditorTemplate(os.ExpandEnv(a), file, line, column, false)
+	}
+	return args, nil
+}
+
+// openURLCommand is the command handing u to the desktop's URL handler.
+func openURLCommand(u string) []string {
+	if runtime.GOOS == "darwin" {
+		return []string{"open", u}
+	}
+	return []string{"xdg-open", u}
+}
+
+// openURL opens u with the desktop's URL handler without waiting for it.
+func openURL(u string) error {
+	args := openURLCommand(u)
+	return exec.Command(args[0], args[1:]...).Start()
+}
+
+// checkEditorPosition rejects a line or column that is not a plain number, as
+// both end up in editor commands and URLs.
+func checkEditorPosition(line, column string) error {
+	for _, s := range []string{line, column} {
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return fmt.Errorf("bad line or column %q", s)
+			}
+		}
+	}
+	return nil
+}
+
+// openInEditor opens file at line and column with the profile selected in
+// prefs.
+func openInEditor(file, line, column string) error {
+	if err := checkEditorPosition(line, column); err != nil {
+		return err
+	}
+	prefsMu.Lock()
+	p := findEditor(editorProfiles(curPrefs.Editors), curPrefs.Editor)
+	prefsMu.Unlock()
+	args, err := editorCommand(p, file, line, column)
+	if err != nil {
+		return err
+	}
+	return exec.Command(args[0], args[1:]...).Start()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("NVIM_LISTEN_ADDRESS", "/tmp/nvim.sock")
+	url := openURLCommand("")[0]
+	tests := []struct {
+		editor             string
+		file, line, column string
+		want               []string
+	}{
+		{"phpstorm", "/Users/me/app/My File.php", "265", "", []string{url, "phpstorm://open?file=/Users/me/app/My%20File.php&line=265"}},
+		{"vscode", "/src/main.go", "12", "7", []string{url, "vscode://file/src/main.go:12:7"}},
+		{"vscode", "/src/a&b.go", "", "", []string{url, "vscode://file/src/a%26b.go:1:1"}},
+		{"goland", "/src/main.go", "12", "", []string{"goland", "--line", "12", "--column", "1", "/src/main.go"}},
+		{"sublime", "/src/My File.go", "3", "4", []string{"subl", "/src/My File.go:3:4"}},
+		{"neovim", "/src/main.go", "9", "", []string{"nvim", "--server", "/tmp/nvim.sock", "--remote", "+9", "/src/main.go"}},
+		{"neovim", "/src/$HOME<CR>:!id<CR>.go", "9", "", []string{"nvim", "--server", "/tmp/nvim.sock", "--remote", "+9", "/src/$HOME<CR>:!id<CR>.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			got, err := editorCommand(findEditor(builtinEditors, tt.editor), tt.file, tt.line, tt.column)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckEditorPosition(t *testing.T) {
+	assert.NoError(t, checkEditorPosition("12", ""))
+	assert.Error(t, checkEditorPosition("1<CR>:!id<CR>", "1"))
+	assert.Error(t, checkEditorPosition("1", "-2"))
+}
+
+func TestEditorProfiles(t *testing.T) {
+	custom := []editorProfile{
+		{Name: "vscode", Command: []string{"code", "-g", "{file}:{line}:{column}"}},
+		{Name: "zed", Command: []string{"zed", "{file}:{line}"}},
+		{Name: ""},
+	}
+	profiles := editorProfiles(custom)
+	require.Len(t, profiles, len(builtinEditors)+1)
+	assert.Equal(t, custom[0], findEditor(profiles, "vscode"), "custom profiles replace built-ins")
+	assert.Equal(t, "zed", profiles[len(profiles)-1].Name)
+	assert.Equal(t, "phpstorm", findEditor(profiles, "").Name)
+	assert.Equal(t, "phpstorm", findEditor(profiles, "missing").Name)
+
+	_, err := editorCommand(editorProfile{Name: "broken"}, "/a", "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logEntry is a Log Entry decoded the same way the viewer's buildEntry does,
+// for Go-side consumers that need its level, message or fields.
+type logEntry struct {
+	Source  string
+	Raw     string
+	Level   string
+	Message string
+	Service string
+	Time    time.Time
+	Fields  map[string]any // nil for non-JSON lines
+}
+
+func parseEntry(source, line string) logEntry {
+	e := logEntry{Source: source, Raw: line, Message: line}
+	var obj map[string]any
+	if json.Unmarshal([]byte(line), &obj) != nil || obj == nil {
+		return e
+	}
+	e.Fields = obj
+	e.Level = normalizeLevel(firstTruthy(obj, "level_name", "dd_status", "level"))
+	if msg := firstTruthy(obj, "message", "msg", "error"); msg != nil {
+		e.Message = fieldString(msg)
+	}
+	for _, key := range []string{"channel", "service", "logger", "dd.service"} {
+		if v, ok := e.lookup(key); ok && fieldString(v) != "" {
+			e.Service = fieldString(v)
+			break
+		}
+	}
+	e.Time = entryTime(obj)
+	return e
+}
+
+// firstTruthy returns the first value under keys that JavaScript would treat
+// as truthy, mirroring the `a || b || c` chains in index.html.
+func firstTruthy(obj map[string]any, keys ...string) any {
+	for _, k := range keys {
+		switch v := obj[k].(type) {
+		case nil:
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			if v != 0 {
+				return v
+			}
+		case bool:
+			if v {
+				return v
+			}
+		default:
+			return v
+		}
+	}
+	return nil
+}
+
+// normalizeLevel maps pino numeric levels and level aliases onto the viewer's
+// INFO / WARN / ERROR / CRITICAL / DEBUG badges.
+func normalizeLevel(v any) string {
+	switch l := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		switch {
+		case l >= 60:
+			return "CRITICAL"
+		case l >= 50:
+			return "ERROR"
+		case l >= 40:
+			return "WARN"
+		case l >= 30:
+			return "INFO"
+		default:
+			return "DEBUG"
+		}
+	}
+	level := strings.ToUpper(fieldString(v))
+	switch level {
+	case "WARNING":
+		return "WARN"
+	case "FATAL":
+		return "CRITICAL"
+	}
+	return level
+}
+
+// lookup resolves a dot-separated property path like getNestedValue does. A
+// key that itself contains the dots, like Datadog's flat "dd.trace_id", wins.
+func (e logEntry) lookup(path string) (any, bool) {
+	if v, ok := e.Fields[path]; ok {
+		return v, true
+	}
+	var cur any = e.Fields
+	for _, p := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// field returns the value the viewer shows for name: the derived level,
+// service and source for "level", "service" and "_source", else the property
+// at that path.
+func (e logEntry) field(name string) (any, bool) {
+	switch name {
+	case "_source":
+		return e.Source, true
+	case "level":
+		return e.Level, e.Level != ""
+	case "service":
+		return e.Service, e.Service != ""
+	}
+	return e.lookup(name)
+}
+
+// fieldString renders a scalar property the way the viewer's filters and
+// columns do; objects, arrays and null render as "".
+func fieldString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return ""
+}
+
+// fieldNumber returns v as a number when it is numeric or a numeric string.
+func fieldNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
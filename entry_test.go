@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		level   string
+		message string
+		service string
+	}{
+		{"monolog", `{"level_name":"WARNING","message":"slow","channel":"api"}`, "WARN", "slow", "api"},
+		{"pino numeric level", `{"level":50,"msg":"boom","service":"worker"}`, "ERROR", "boom", "worker"},
+		{"pino fatal", `{"level":60,"msg":"dead"}`, "CRITICAL", "dead", ""},
+		{"datadog", `{"dd_status":"fatal","error":"oops","dd":{"service":"checkout"}}`, "CRITICAL", "oops", "checkout"},
+		{"empty message falls through", `{"level":"info","message":"","msg":"fallback"}`, "INFO", "fallback", ""},
+		{"plain text", "not json", "", "not json", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parseEntry("app.log", tt.line)
+			assert.Equal(t, tt.level, e.Level)
+			assert.Equal(t, tt.message, e.Message)
+			assert.Equal(t, tt.service, e.Service)
+			assert.Equal(t, "app.log", e.Source)
+		})
+	}
+}
+
+func TestLogEntryField(t *testing.T) {
+	e := parseEntry("a.log", `{"level":30,"context":{"user_id":42,"tags":["x"]},"ok":true}`)
+
+	v, ok := e.field("context.user_id")
+	assert.True(t, ok)
+	assert.Equal(t, "42", fieldString(v))
+
+	v, ok = e.field("level")
+	assert.True(t, ok)
+	assert.Equal(t, "INFO", v)
+
+	v, _ = e.field("_source")
+	assert.Equal(t, "a.log", v)
+
+	v, _ = e.field("ok")
+	assert.Equal(t, "true", fieldString(v))
+
+	v, _ = e.field("context.tags")
+	assert.Equal(t, "", fieldString(v), "arrays render empty like in the viewer")
+
+	_, ok = e.field("context.missing")
+	assert.False(t, ok)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFilter mirrors the viewer's filters so /export writes exactly the
+// entries the list shows: the property and level filters arrive as a query,
+// the timeline brush as From/To and the pattern chips as pattern IDs.
+type exportFilter struct {
+	Where    *query
+	From, To time.Time // zero for no time window; To is exclusive
+	Only     int       // pattern ID, 0 for any
+	Hide     []int
+}
+
+// parseExportFilter reads where, from, to, pattern and hide from an /export
+// request.
+func parseExportFilter(q url.Values) (exportFilter, error) {
+	var f exportFilter
+	var err error
+	if f.Where, err = parseQuery(q.Get("where")); err != nil {
+		return f, err
+	}
+	if f.From, err = parseTimeArg(q.Get("from")); err != nil {
+		return f, err
+	}
+	if f.To, err = parseTimeArg(q.Get("to")); err != nil {
+		return f, err
+	}
+	if v := q.Get("pattern"); v != "" {
+		if f.Only, err = strconv.Atoi(v); err != nil || f.Only <= 0 {
+			return f, fmt.Errorf("bad pattern %q", v)
+		}
+	}
+	for _, v := range q["hide"] {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return f, fmt.Errorf("bad hide %q", v)
+		}
+		f.Hide = append(f.Hide, id)
+	}
+	return f, nil
+}
+
+// match reports whether e, filed under pattern, passes f.
+func (f exportFilter) match(e logEntry, pattern int) bool {
+	if !f.From.IsZero() && (e.Time.IsZero() || e.Time.Before(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && (e.Time.IsZero() || !e.Time.Before(f.To)) {
+		return false
+	}
+	if f.Only != 0 && pattern != f.Only {
+		return false
+	}
+	if slices.Contains(f.Hide, pattern) {
+		return false
+	}
+	return f.Where.match(e)
+}
+
+// exportFormats maps the accepted format names to file extension and
+// Content-Type.
+var exportFormats = map[string][2]string{
+	"jsonl":  {"jsonl", "application/x-ndjson"},
+	"ndjson": {"jsonl", "application/x-ndjson"},
+	"csv":    {"csv", "text/csv; charset=utf-8"},
+	"md":     {"md", "text/markdown; charset=utf-8"},
+}
+
+// exportColumns are the built-in list columns, in display order, followed by
+// any Custom Columns.
+func exportColumns(custom []string) []string {
+	return append([]string{"source", "time", "level", "message"}, custom...)
+}
+
+func exportCell(e logEntry, col string, custom bool) string {
+	if !custom {
+		switch col {
+		case "source":
+			return e.Source
+		case "time":
+			if v := firstTruthy(e.Fields, timeKeys...); v != nil {
+				return fieldString(v)
+			}
+			return ""
+		case "level":
+			return e.Level
+		case "message":
+			return e.Message
+		}
+	}
+	v, _ := e.field(col)
+	return fieldString(v)
+}
+
+// annotate adds note to a JSON object line as "_note", leaving the rest of the
+// line byte for byte; other lines, including invalid JSON and objects that
+// already have a "_note", are returned unchanged.
+func annotate(line, note string) string {
+	t := strings.TrimSpace(line)
+	if note == "" || !strings.HasPrefix(t, "{") || !strings.HasSuffix(t, "}") {
+		return line
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(t), &obj); err != nil {
+		return line
+	}
+	if _, ok := obj["_note"]; ok {
+		return line
+	}
+	q, _ := json.Marshal(note)
+	body := strings.TrimSpace(t[1 : len(t)-1])
+	if body == "" {
+		return `{"_note":` + string(q) + "}"
+	}
+	return t[:len(t)-1] + `,"_note":` + string(q) + "}"
+}
+
+// writeExport writes the entries of msgs matching f as "jsonl" (raw lines),
+// "csv" or "md" (a Markdown table), the latter two with columns from
+// exportColumns(custom). Bookmark notes (keyed like bookmark.key) go into a
+// "_note" property of JSON lines, or a trailing note column when any exported
+// entry has one.
+func writeExport(out io.Writer, msgs []logMsg, f exportFilter, format string, custom []string, notes map[string]string) error {
+	var entries []logEntry
+	var entryNotes []string
+	hasNotes := false
+	for _, m := range msgs {
+		if e := parseEntry(m.S, m.D); f.match(e, m.M) {
+			entries = append(entries, e)
+			note := msgNote(notes, m)
+			entryNotes = append(entryNotes, note)
+			hasNotes = hasNotes || note != ""
+		}
+	}
+	base := exportColumns(custom)
+	cols := base
+	if hasNotes {
+		cols = append(cols[:len(cols):len(cols)], "note")
+	}
+	row := func(i int) []string {
+		cells := make([]string, 0, len(cols))
+		for j, c := range base {
+			cells = append(cells, exportCell(entries[i], c, j >= len(base)-len(custom)))
+		}
+		if hasNotes {
+			cells = append(cells, entryNotes[i])
+		}
+		return cells
+	}
+
+	switch format {
+	case "jsonl", "ndjson":
+		for i, e := range entries {
+			if _, err := io.WriteString(out, annotate(e.Raw, entryNotes[i])+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(out)
+		cw.Write(cols) //nolint:errcheck
+		for i := range entries {
+			cw.Write(row(i)) //nolint:errcheck
+		}
+		cw.Flush()
+		return cw.Error()
+	case "md":
+		cell := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+		line := func(cells []string) error {
+			for i := range cells {
+				cells[i] = cell.Replace(cells[i])
+			}
+			_, err := fmt.Fprintf(out, "| %s |\n", strings.Join(cells, " | "))
+			return err
+		}
+		if err := line(append([]string(nil), cols...)); err != nil {
+			return err
+		}
+		sep := make([]string, len(cols))
+		for i := range sep {
+			sep[i] = "---"
+		}
+		if err := line(sep); err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := line(row(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
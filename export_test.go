@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportMsgs = []logMsg{
+	{S: "api.log", D: `{"time":"2024-01-15T10:00:00Z","level":"info","msg":"GET /orders took 12ms","user":"ann"}`, M: 1},
+	{S: "api.log", D: `{"time":"2024-01-15T10:01:00Z","level":"error","msg":"payment | declined","user":"bob"}`, M: 2},
+	{S: "worker.log", D: `{"time":"2024-01-15T10:02:00Z","level":"error","msg":"GET /orders took 40ms"}`, M: 1},
+	{S: "worker.log", D: `plain text line`, M: 3},
+}
+
+func export(t *testing.T, params, format string, cols ...string) string {
+	t.Helper()
+	q, err := url.ParseQuery(params)
+	require.NoError(t, err)
+	f, err := parseExportFilter(q)
+	require.NoError(t, err)
+	var sb strings.Builder
+	require.NoError(t, writeExport(&sb, exportMsgs, f, format, cols, nil))
+	return sb.String()
+}
+
+func TestExportJSONLKeepsRawLines(t *testing.T) {
+	out := export(t, "where=level+%3D%3D+ERROR", "jsonl")
+	assert.Equal(t, exportMsgs[1].D+"\n"+exportMsgs[2].D+"\n", out)
+}
+
+func TestExportTimeWindowAndPatterns(t *testing.T) {
+	out := export(t, "from=2024-01-15T10:00:30Z&to=2024-01-15T10:02:00Z", "jsonl")
+	assert.Equal(t, exportMsgs[1].D+"\n", out, "to is exclusive, untimed entries are outside any window")
+
+	out = export(t, "pattern=1", "jsonl")
+	assert.Equal(t, exportMsgs[0].D+"\n"+exportMsgs[2].D+"\n", out)
+
+	out = export(t, "hide=1&hide=3", "jsonl")
+	assert.Equal(t, exportMsgs[1].D+"\n", out)
+}
+
+func TestExportCSVUsesBuiltinAndCustomColumns(t *testing.T) {
+	out := export(t, "where=_source+%3D%3D+api.log", "csv", "user")
+	assert.Equal(t, "source,time,level,message,user\n"+
+		"api.log,2024-01-15T10:00:00Z,INFO,GET /orders took 12ms,ann\n"+
+		"api.log,2024-01-15T10:01:00Z,ERROR,payment | declined,bob\n", out)
+}
+
+func TestExportMarkdownEscapesPipes(t *testing.T) {
+	out := export(t, "where=user+%3D%3D+bob", "md")
+	assert.Equal(t, "| source | time | level | message |\n"+
+		"| --- | --- | --- | --- |\n"+
+		`| api.log | 2024-01-15T10:01:00Z | ERROR | payment \| declined |`+"\n", out)
+}
+
+func TestParseExportFilterRejectsBadQuery(t *testing.T) {
+	_, err := parseExportFilter(url.Values{"where": {"level =="}})
+	assert.Error(t, err)
+	_, err = parseExportFilter(url.Values{"pattern": {"GET /orders took <*>"}})
+	assert.Error(t, err)
+	_, err = parseExportFilter(url.Values{"hide": {"0"}})
+	assert.Error(t, err)
+}
+
+func TestExportEmptyValueFilterMatchesMissingProperty(t *testing.T) {
+	// The viewer sends this for a property filter on the "" value.
+	out := export(t, "where="+url.QueryEscape(`(user == "" or not user ~ "")`), "jsonl")
+	assert.Equal(t, exportMsgs[2].D+"\n"+exportMsgs[3].D+"\n", out)
+}
+
+func TestExportIncludesBookmarkNotes(t *testing.T) {
+	notes := map[string]string{
+		bookmarkKey("api.log", nil, lineHash(exportMsgs[1].D)): "root cause",
+	}
+	var sb strings.Builder
+	require.NoError(t, writeExport(&sb, exportMsgs[:2], exportFilter{}, "jsonl", nil, notes))
+	assert.Equal(t, exportMsgs[0].D+"\n"+
+		`{"time":"2024-01-15T10:01:00Z","level":"error","msg":"payment | declined","user":"bob","_note":"root cause"}`+"\n", sb.String())
+
+	sb.Reset()
+	require.NoError(t, writeExport(&sb, exportMsgs[:2], exportFilter{}, "csv", nil, notes))
+	assert.Equal(t, "source,time,level,message,note\n"+
+		"api.log,2024-01-15T10:00:00Z,INFO,GET /orders took 12ms,\n"+
+		"api.log,2024-01-15T10:01:00Z,ERROR,payment | declined,root cause\n", sb.String())
+}
+
+func TestAnnotateLeavesNonObjectLinesAlone(t *testing.T) {
+	assert.Equal(t, "plain", annotate("plain", "x"))
+	assert.Equal(t, `{"_note":"x"}`, annotate("{ }", "x"))
+	assert.Equal(t, `{"a":1}`, annotate(`{"a":1}`, ""))
+	assert.Equal(t, `{"a":1,}`, annotate(`{"a":1,}`, "x"), "invalid JSON")
+	assert.Equal(t, `{"a":"}{"`, annotate(`{"a":"}{"`, "x"), "invalid JSON")
+	assert.Equal(t, `{"_note":"old","a":1}`, annotate(`{"_note":"old","a":1}`, "x"))
+}
@@ -9,41 +9,34 @@ package main
 import "C"
 
 //export cMenuOpenFiles
-func cMenuOpenFiles() {
-	select {
-	case menuFileCh <- "open":
-	default:
-	}
-}
+func cMenuOpenFiles() { sendMenuAction("open", false) }
 
 //export cOpenFile
-func cOpenFile(path *C.char) {
-	menuFileCh <- C.GoString(path)
-}
+func cOpenFile(path *C.char) { sendMenuAction(C.GoString(path), true) }
 
 //export cClearRecent
-func cClearRecent() {
-	select {
-	case menuFileCh <- "clear":
-	default:
-	}
-}
+func cClearRecent() { sendMenuAction("clear", false) }
 
 //export cRestartApp
-func cRestartApp() {
-	select {
-	case menuFileCh <- "restart":
-	default:
-	}
-}
+func cRestartApp() { sendMenuAction("restart", false) }
 
 //export cClearLogFiles
-func cClearLogFiles() {
-	select {
-	case menuFileCh <- "clear-log-files":
-	default:
-	}
-}
+func cClearLogFiles() { sendMenuAction("clear-log-files", false) }
+
+//export cExport
+func cExport() { sendMenuAction("export", false) }
+
+//export cSaveSession
+func cSaveSession() { sendMenuAction("save-session", false) }
+
+//export cOpenSession
+func cOpenSession() { sendMenuAction("open-session", false) }
+
+//export cSwitchWorkspace
+func cSwitchWorkspace(name *C.char) { sendMenuAction("workspace:"+C.GoString(name), true) }
+
+//export cManageWorkspaces
+func cManageWorkspaces() { sendMenuAction("manage-workspaces", false) }
 
 //export cSaveWindowFrame
 func cSaveWindowFrame(x, y, w, h C.CGFloat) {
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// highlightRule styles the rows whose Log Entry matches Query, e.g.
+// `status_code >= 500` with a red background. The first matching rule wins.
+type highlightRule struct {
+	Query string `json:"query"`
+	Color string `json:"color,omitempty"` // #rrggbb; the row is tinted with it
+	Icon  string `json:"icon,omitempty"`  // shown before the message
+	Bold  bool   `json:"bold,omitempty"`
+}
+
+var hexColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// highlighter evaluates a list of highlight rules.
+type highlighter struct {
+	queries []*query
+}
+
+// newHighlighter compiles rules; every rule needs a valid query and a colour
+// the UI can put into a style.
+func newHighlighter(rules []highlightRule) (*highlighter, error) {
+	h := &highlighter{}
+	for i, r := range rules {
+		q, err := parseQuery(r.Query)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if q == nil {
+			return nil, fmt.Errorf("rule %d: empty query", i+1)
+		}
+		if r.Color != "" && !hexColorRe.MatchString(r.Color) {
+			return nil, fmt.Errorf("rule %d: colour must look like #rrggbb", i+1)
+		}
+		if len([]rune(r.Icon)) > 4 {
+			return nil, fmt.Errorf("rule %d: icon too long", i+1)
+		}
+		h.queries = append(h.queries, q)
+	}
+	return h, nil
+}
+
+// match returns 1 + the index of the first rule e matches, or 0.
+func (h *highlighter) match(e logEntry) int {
+	if h == nil {
+		return 0
+	}
+	for i, q := range h.queries {
+		if q.match(e) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+var (
+	highlightMu    sync.Mutex
+	curHighlighter *highlighter
+)
+
+// setHighlights makes rules the ones entries are evaluated against.
+func setHighlights(rules []highlightRule) error {
+	h, err := newHighlighter(rules)
+	if err != nil {
+		return err
+	}
+	highlightMu.Lock()
+	curHighlighter = h
+	highlightMu.Unlock()
+	return nil
+}
+
+// highlightMsg sets msg.H to the rule msg matches. It runs after redactMsg,
+// so rules see only what the UI shows.
+func highlightMsg(msg logMsg) logMsg {
+	highlightMu.Lock()
+	h := curHighlighter
+	highlightMu.Unlock()
+	if h == nil || len(h.queries) == 0 {
+		msg.H = 0
+		return msg
+	}
+	msg.H = h.match(parseEntry(msg.S, msg.D))
+	return msg
+}
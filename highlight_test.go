@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlighterFirstMatchWins(t *testing.T) {
+	h, err := newHighlighter([]highlightRule{
+		{Query: "status_code >= 500", Color: "#f85149", Bold: true},
+		{Query: "user_id == 42", Color: "#d29922", Icon: "★"},
+		{Query: "timeout"},
+	})
+	require.NoError(t, err)
+	for line, want := range map[string]int{
+		`{"status_code":503,"user_id":42}`:     1,
+		`{"status_code":200,"user_id":42}`:     2,
+		`{"status_code":200,"user_id":"42"}`:   2,
+		`{"message":"upstream Timeout"}`:       3,
+		`{"status_code":404,"user_id":7}`:      0,
+		`plain line without any of the above`: 0,
+	} {
+		assert.Equal(t, want, h.match(parseEntry("app.log", line)), line)
+	}
+	assert.Zero(t, (*highlighter)(nil).match(parseEntry("", "x")))
+}
+
+func TestNewHighlighterValidates(t *testing.T) {
+	for _, rules := range [][]highlightRule{
+		{{Query: ""}},
+		{{Query: "status_code >="}},
+		{{Query: "a == 1", Color: "red;background:url(x)"}},
+		{{Query: "a == 1", Icon: "too long icon"}},
+	} {
+		_, err := newHighlighter(rules)
+		assert.Error(t, err, rules)
+	}
+	_, err := newHighlighter(nil)
+	assert.NoError(t, err)
+}
+
+func TestHighlightMsg(t *testing.T) {
+	t.Cleanup(func() { setHighlights(nil) }) //nolint:errcheck
+	b := newBroker()
+	b.publish("a.log", `{"status_code":500}`)
+	assert.Zero(t, b.snapshot()[0].H)
+
+	require.NoError(t, setHighlights([]highlightRule{{Query: "status_code >= 500", Color: "#f85149"}}))
+	b.publish("a.log", `{"status_code":502}`)
+	b.publish("a.log", `{"status_code":200}`)
+	hist := b.snapshot()
+	assert.Equal(t, []int{0, 1, 0}, []int{hist[0].H, hist[1].H, hist[2].H})
+
+	// Changed rules reach the entries already buffered.
+	b.rehighlight()
+	assert.Equal(t, 1, b.snapshot()[0].H)
+	require.NoError(t, setHighlights(nil))
+	b.rehighlight()
+	assert.Zero(t, b.snapshot()[1].H)
+}
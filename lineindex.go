@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// indexSaveEvery is how many newly indexed bytes trigger a rewrite of the
+// persisted index. Smaller files are cheap enough to re-scan on every launch.
+const indexSaveEvery = 4 << 20
+
+// indexPrintLen is how many bytes at the start and at the end of the indexed
+// part of a file are hashed to tell it from another file with the same inode.
+const indexPrintLen = 4 << 10
+
+// fileID identifies a file across renames: an inode is only unique per device.
+type fileID struct {
+	dev, ino uint64
+}
+
+// lineIndex records where every complete line of a file ends, so arbitrary
+// pages of lines can be read without scanning from the start.
+type lineIndex struct {
+	scan  sync.Mutex // serialises update; only the holder writes id and ends
+	mu    sync.Mutex // guards ends and saved for readers
+	path  string
+	id    fileID
+	ends  []int64 // ends[i] is the offset just past line i's newline
+	saved int64   // bytes covered by the persisted index file
+}
+
+var (
+	lineIndexMu sync.Mutex
+	lineIndexes = map[string]*lineIndex{} // path → index
+)
+
+// lineIndexFor returns the shared index for path, creating it on first use.
+func lineIndexFor(path string) *lineIndex {
+	lineIndexMu.Lock()
+	defer lineIndexMu.Unlock()
+	li, ok := lineIndexes[path]
+	if !ok {
+		li = &lineIndex{path: path}
+		lineIndexes[path] = li
+	}
+	return li
+}
+
+// dropLineIndex forgets the index of path once it is no longer open.
+func dropLineIndex(path string) {
+	lineIndexMu.Lock()
+	delete(lineIndexes, path)
+	lineIndexMu.Unlock()
+}
+
+func indexDir() string {
+	return filepath.Join(configDir(), "index")
+}
+
+func fileIdentity(info os.FileInfo) fileID {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileID{uint64(st.Dev), uint64(st.Ino)}
+	}
+	return fileID{}
+}
+
+// fingerprint hashes the first and the last indexPrintLen bytes of the first
+// size bytes of f.
+func fingerprint(f *os.File, size int64) ([sha256.Size]byte, error) {
+	h := sha256.New()
+	head := make([]byte, min(size, indexPrintLen))
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	h.Write(head)
+	tail := make([]byte, min(size, indexPrintLen))
+	if _, err := f.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	h.Write(tail)
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// size returns the number of bytes covered by the index. Must be called with
+// scan or mu held.
+func (li *lineIndex) size() int64 {
+	if len(li.ends) == 0 {
+		return 0
+	}
+	return li.ends[len(li.ends)-1]
+}
+
+// update brings the index in line with the file on disk: it resets after
+// rotation or truncation, resumes from a persisted index when one matches,
+// and scans whatever has been appended since. The scan runs without mu, so
+// readers see the previous state until it is done.
+func (li *lineIndex) update() error {
+	li.scan.Lock()
+	defer li.scan.Unlock()
+
+	f, err := os.Open(li.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	id := fileIdentity(info)
+	if id != li.id || info.Size() < li.size() {
+		ends, saved := loadLineIndex(f, id, info)
+		li.mu.Lock()
+		li.id, li.ends, li.saved = id, ends, saved
+		li.mu.Unlock()
+	}
+
+	pos := li.size()
+	var ends []int64
+	r := bufio.NewReaderSize(io.NewSectionReader(f, pos, info.Size()-pos), 64*1024)
+	for {
+		chunk, err := r.ReadSlice('\n')
+		pos += int64(len(chunk))
+		if err == nil {
+			ends = append(ends, pos)
+			continue
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		break
+	}
+	li.mu.Lock()
+	li.ends = append(li.ends, ends...)
+	li.mu.Unlock()
+
+	if li.id != (fileID{}) && li.size()-li.saved >= indexSaveEvery {
+		li.save(f, info.ModTime())
+	}
+	return nil
+}
+
+func indexFileGlob(id fileID) string {
+	return filepath.Join(indexDir(), fmt.Sprintf("%d-%d-*.idx", id.dev, id.ino))
+}
+
+// loadLineIndex returns the largest persisted index for file id that does not
+// extend past the file's size, and the bytes it covers. An index is only
+// trusted if the file was not modified before it was saved and still hashes
+// to the fingerprint saved with it; a reused inode gets a fresh index.
+func loadLineIndex(f *os.File, id fileID, info os.FileInfo) ([]int64, int64) {
+	if id == (fileID{}) {
+		return nil, 0
+	}
+	matches, _ := filepath.Glob(indexFileGlob(id))
+	var best string
+	var bestSize int64 = -1
+	for _, m := range matches {
+		var dev, ino uint64
+		var n int64
+		if _, err := fmt.Sscanf(filepath.Base(m), "%d-%d-%d.idx", &dev, &ino, &n); err != nil {
+			continue
+		}
+		if n <= info.Size() && n > bestSize {
+			best, bestSize = m, n
+		}
+	}
+	if best == "" || bestSize == 0 {
+		return nil, 0
+	}
+	data, err := os.ReadFile(best)
+	if err != nil {
+		return nil, 0
+	}
+	mtime, n := binary.Varint(data)
+	if n <= 0 || len(data) < n+sha256.Size {
+		return nil, 0
+	}
+	saved := time.Unix(0, mtime)
+	if info.ModTime().Before(saved) || info.Size() == bestSize && !info.ModTime().Equal(saved) {
+		return nil, 0
+	}
+	sum, err := fingerprint(f, bestSize)
+	if err != nil || !bytes.Equal(sum[:], data[n:n+sha256.Size]) {
+		return nil, 0
+	}
+	data = data[n+sha256.Size:]
+	var ends []int64
+	var prev int64
+	for len(data) > 0 {
+		d, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, 0
+		}
+		data = data[n:]
+		prev += int64(d)
+		ends = append(ends, prev)
+	}
+	if len(ends) == 0 || ends[len(ends)-1] != bestSize {
+		return nil, 0
+	}
+	return ends, bestSize
+}
+
+// save persists the index under <dev>-<inode>-<size>.idx: the file's mtime
+// and fingerprint, then the line ends as delta-encoded varints. Older
+// snapshots for the same file are removed. Must be called with scan held.
+func (li *lineIndex) save(f *os.File, mtime time.Time) {
+	dir := indexDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	size := li.size()
+	sum, err := fingerprint(f, size)
+	if err != nil {
+		return
+	}
+	buf := make([]byte, 0, len(li.ends)*2+binary.MaxVarintLen64+len(sum))
+	buf = binary.AppendVarint(buf, mtime.UnixNano())
+	buf = append(buf, sum[:]...)
+	var prev int64
+	for _, end := range li.ends {
+		buf = binary.AppendUvarint(buf, uint64(end-prev))
+		prev = end
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%d-%d-%d.idx", li.id.dev, li.id.ino, size))
+	if os.WriteFile(name, buf, 0o644) != nil {
+		return
+	}
+	li.mu.Lock()
+	li.saved = size
+	li.mu.Unlock()
+	old, _ := filepath.Glob(indexFileGlob(li.id))
+	for _, m := range old {
+		if m != name {
+			os.Remove(m) //nolint:errcheck
+		}
+	}
+}
+
+// readLines returns up to limit lines starting at line number offset together
+// with the total number of complete lines in the file.
+func (li *lineIndex) readLines(offset, limit int) (int, []string, error) {
+	if err := li.update(); err != nil {
+		return 0, nil, err
+	}
+	li.mu.Lock()
+	total := len(li.ends)
+	if offset >= total || limit <= 0 {
+		li.mu.Unlock()
+		return total, nil, nil
+	}
+	end := min(offset+limit, total)
+	var start int64
+	if offset > 0 {
+		start = li.ends[offset-1]
+	}
+	ends := append([]int64(nil), li.ends[offset:end]...)
+	li.mu.Unlock()
+
+	f, err := os.Open(li.path)
+	if err != nil {
+		return total, nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, ends[len(ends)-1]-start)
+	if _, err := f.ReadAt(buf, start); err != nil {
+		return total, nil, err
+	}
+	lines := make([]string, len(ends))
+	pos := start
+	for i, e := range ends {
+		lines[i] = strings.TrimRight(string(buf[pos-start:e-start]), "\r\n")
+		pos = e
+	}
+	return total, lines, nil
+}
+
+// lineOffset returns the byte offset at which line number n starts.
+func (li *lineIndex) lineOffset(n int) (int64, error) {
+	if err := li.update(); err != nil {
+		return 0, err
+	}
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	if n <= 0 || len(li.ends) == 0 {
+		return 0, nil
+	}
+	return li.ends[min(n, len(li.ends))-1], nil
+}
+
+// lineStarts returns the byte offsets of n lines from line number offset as
+// of the last update; lines the index does not cover yet get -1.
+func (li *lineIndex) lineStarts(offset, n int) []int64 {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	starts := make([]int64, n)
+	for i := range starts {
+		switch l := offset + i; {
+		case l == 0:
+			starts[i] = 0
+		case l <= len(li.ends):
+			starts[i] = li.ends[l-1]
+		default:
+			starts[i] = -1
+		}
+	}
+	return starts
+}
+
+// indexInBackground starts building the line index for path so the first
+// /lines request for it does not have to scan the whole file.
+func indexInBackground(path string) {
+	go lineIndexFor(path).update() //nolint:errcheck
+}
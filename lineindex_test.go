@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineIndexReadLines(t *testing.T) {
+	p := writeTempLog(t, "a\nb\nc\nd\ne\npartial")
+	li := &lineIndex{path: p}
+
+	total, lines, err := li.readLines(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"b", "c", "d"}, lines)
+
+	total, lines, err = li.readLines(4, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"e"}, lines)
+
+	_, lines, err = li.readLines(5, 10)
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
+
+func TestLineIndexFollowsAppendsAndTruncation(t *testing.T) {
+	p := writeTempLog(t, "a\nb\n")
+	li := &lineIndex{path: p}
+	total, _, err := li.readLines(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("c\r\nd\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	total, lines, err := li.readLines(2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"c", "d"}, lines)
+
+	require.NoError(t, os.Truncate(p, 0))
+	total, _, err = li.readLines(0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func TestLineIndexPersistsAndResumes(t *testing.T) {
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	var sb strings.Builder
+	for i := 0; sb.Len() < indexSaveEvery; i++ {
+		fmt.Fprintf(&sb, `{"message":"line %d"}`+"\n", i)
+	}
+	p := filepath.Join(t.TempDir(), "big.log")
+	require.NoError(t, os.WriteFile(p, []byte(sb.String()), 0o644))
+
+	first := &lineIndex{path: p}
+	require.NoError(t, first.update())
+	matches, _ := filepath.Glob(filepath.Join(indexDir(), "*.idx"))
+	require.Len(t, matches, 1)
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("tail\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	second := &lineIndex{path: p}
+	total, lines, err := second.readLines(len(first.ends), 1)
+	require.NoError(t, err)
+	assert.Equal(t, len(first.ends)+1, total)
+	assert.Equal(t, []string{"tail"}, lines)
+	assert.Equal(t, first.size(), second.saved, "resumed from the persisted index")
+}
+
+func TestLineIndexIgnoresIndexOfReplacedContent(t *testing.T) {
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	var sb strings.Builder
+	for i := 0; sb.Len() < indexSaveEvery; i++ {
+		fmt.Fprintf(&sb, `{"message":"line %d"}`+"\n", i)
+	}
+	p := filepath.Join(t.TempDir(), "big.log")
+	require.NoError(t, os.WriteFile(p, []byte(sb.String()), 0o644))
+	first := &lineIndex{path: p}
+	require.NoError(t, first.update())
+	info, err := os.Stat(p)
+	require.NoError(t, err)
+
+	// Same inode and size, different lines: as after rotating into a reused inode.
+	other := strings.ReplaceAll(sb.String(), `"line `, `"l `)
+	other += strings.Repeat("x", sb.Len()-len(other)-1) + "\n"
+	require.NoError(t, os.WriteFile(p, []byte(other), 0o644))
+	require.NoError(t, os.Chtimes(p, info.ModTime(), info.ModTime())) // only the fingerprint differs
+
+	second := &lineIndex{path: p}
+	total, lines, err := second.readLines(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Count(other, "\n"), total)
+	assert.Equal(t, []string{`{"message":"l 0"}`}, lines)
+}
+
+func TestWatcherRemoveDropsLineIndex(t *testing.T) {
+	p := writeTempLog(t, "a\n")
+	w := NewWatcher(newBroker())
+	w.Register(p)
+	w.Remove(p)
+	lineIndexMu.Lock()
+	_, ok := lineIndexes[p]
+	lineIndexMu.Unlock()
+	assert.False(t, ok)
+}
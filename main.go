@@ -4,15 +4,18 @@ import (
 	"bufio"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html"
 	"io"
+	"maps"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -26,30 +29,129 @@ var htmlContent string
 
 const maxHistory = 50000
 
+// maxPageLines caps the page size served by /lines.
+const maxPageLines = 5000
+
 // logMsg is the envelope sent over SSE.
 type logMsg struct {
-	S string `json:"s"` // source: basename of file, or "" for stdin
-	D string `json:"d"` // data:   original log line
+	S string `json:"s"`           // source: basename of file, or "" for stdin
+	D string `json:"d"`           // data:   original log line
+	N int    `json:"n,omitempty"` // repeats: number of folded consecutive duplicates
+	F string `json:"f,omitempty"` // first:  time of the first folded repeat
+	L string `json:"l,omitempty"` // last:   time of the latest folded repeat
+	U bool   `json:"u,omitempty"` // update: replaces the source's previous entry
+	O *int64 `json:"o,omitempty"` // offset: byte offset of the line in its file, if known
+	R int64  `json:"r,omitempty"` // redacted: id of the masked original, see redactMsg
+	H int    `json:"h,omitempty"` // highlight: 1 + index of the matching highlight rule
+	M int    `json:"m,omitempty"` // pattern: ID of the mined message pattern, see patternMiner
+	P string `json:"-"`           // path: the file as tracked by the Watcher, if any
 }
 
 type broker struct {
 	mu      sync.Mutex
 	history []logMsg
 	clients map[chan logMsg]struct{}
+
+	// Duplicate folding state; see fold.
+	dedup   bool
+	trimmed int               // entries dropped from the front of history
+	lastKey map[string]string // source → dedup key of its latest entry
+	lastIdx map[string]int    // source → absolute history index of that entry
+
+	// patterns mines every published entry, so each carries its pattern ID.
+	patterns *patternMiner
 }
 
 func newBroker() *broker {
-	return &broker{clients: make(map[chan logMsg]struct{})}
+	return &broker{
+		clients:  make(map[chan logMsg]struct{}),
+		lastKey:  make(map[string]string),
+		lastIdx:  make(map[string]int),
+		patterns: newPatternMiner(),
+	}
 }
 
-func (b *broker) publish(source, line string) {
-	msg := logMsg{S: source, D: line}
+// setDedup turns folding of consecutive duplicates on or off for new entries.
+func (b *broker) setDedup(on bool) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
+	b.dedup = on
+	clear(b.lastKey) // entries added while off were not tracked
+	clear(b.lastIdx)
+	b.mu.Unlock()
+}
+
+// dedupKey returns line without its timestamp, so repeats that differ only in
+// when they happened compare equal.
+func dedupKey(line string) string {
+	var obj map[string]any
+	if json.Unmarshal([]byte(line), &obj) != nil || obj == nil {
+		return line
+	}
+	for _, k := range timeKeys {
+		delete(obj, k)
+	}
+	data, _ := json.Marshal(obj)
+	return string(data)
+}
+
+// add appends msg to history and returns the message to fan out. With dedup
+// on, a repeat of the source's previous entry is folded into it instead and
+// the returned message is an update carrying the new repeat count.
+// Must be called with b.mu held.
+func (b *broker) add(msg logMsg, key string) logMsg {
+	if b.dedup {
+		if i, ok := b.lastIdx[msg.S]; ok && i >= b.trimmed && b.lastKey[msg.S] == key {
+			prev := &b.history[i-b.trimmed]
+			now := foldTime(msg.D)
+			if prev.N == 0 {
+				prev.N = 1
+				prev.F = foldTime(prev.D)
+			}
+			prev.N++
+			prev.L = now
+			upd := *prev
+			upd.U = true
+			return upd
+		}
+		b.lastKey[msg.S] = key
+		b.lastIdx[msg.S] = b.trimmed + len(b.history)
+	}
 	if len(b.history) >= maxHistory {
 		b.history = b.history[1:]
+		b.trimmed++
 	}
 	b.history = append(b.history, msg)
+	return msg
+}
+
+// foldTime is the time recorded for a folded repeat: its own timestamp, or
+// the arrival time when it has none.
+func foldTime(line string) string {
+	t := parseLineTime(line)
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (b *broker) publish(source, line string) {
+	b.publishMsg(logMsg{S: source, D: line})
+}
+
+// publishAt publishes a line read from offset off of the file at path.
+func (b *broker) publishAt(path, source, line string, off int64) {
+	b.publishMsg(logMsg{S: source, D: line, O: &off, P: path})
+}
+
+func (b *broker) publishMsg(msg logMsg) {
+	msg = b.minePattern(highlightMsg(redactMsg(msg)))
+	var key string
+	if b.dedupEnabled() {
+		key = dedupKey(msg.D)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg = b.add(msg, key)
 	for ch := range b.clients {
 		select {
 		case ch <- msg:
@@ -59,15 +161,22 @@ func (b *broker) publish(source, line string) {
 }
 
 func (b *broker) publishBatch(msgs []logMsg) {
-	b.mu.Lock()
-	for _, msg := range msgs {
-		if len(b.history) >= maxHistory {
-			b.history = b.history[1:]
+	for i := range msgs {
+		msgs[i] = b.minePattern(highlightMsg(redactMsg(msgs[i])))
+	}
+	keys := make([]string, len(msgs))
+	if b.dedupEnabled() {
+		for i, msg := range msgs {
+			keys[i] = dedupKey(msg.D)
 		}
-		b.history = append(b.history, msg)
+	}
+	b.mu.Lock()
+	out := make([]logMsg, len(msgs))
+	for i, msg := range msgs {
+		out[i] = b.add(msg, keys[i])
 	}
 	for ch := range b.clients {
-		for _, msg := range msgs {
+		for _, msg := range out {
 			select {
 			case ch <- msg:
 			default:
@@ -77,6 +186,12 @@ func (b *broker) publishBatch(msgs []logMsg) {
 	b.mu.Unlock()
 }
 
+func (b *broker) dedupEnabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dedup
+}
+
 func (b *broker) subscribe() ([]logMsg, chan logMsg) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -87,9 +202,48 @@ func (b *broker) subscribe() ([]logMsg, chan logMsg) {
 	return hist, ch
 }
 
+// snapshot returns a copy of the buffered history.
+func (b *broker) snapshot() []logMsg {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	hist := make([]logMsg, len(b.history))
+	copy(hist, b.history)
+	return hist
+}
+
+// minePattern sets msg.M to the pattern msg is filed under. The UI and /export
+// filter on it, so neither has to match templates again.
+func (b *broker) minePattern(msg logMsg) logMsg {
+	msg.M = b.patterns.add(parseEntry(msg.S, msg.D))
+	return msg
+}
+
 func (b *broker) reset() {
 	b.mu.Lock()
 	b.history = b.history[:0]
+	b.trimmed = 0
+	clear(b.lastKey)
+	clear(b.lastIdx)
+	b.mu.Unlock()
+}
+
+// removeFile drops the entries read from path from history, for a closed
+// file. Other files with the same basename keep theirs.
+func (b *broker) removeFile(path string) {
+	b.mu.Lock()
+	b.history = slices.DeleteFunc(b.history, func(m logMsg) bool { return m.P == path })
+	clear(b.lastKey) // history indexes moved
+	clear(b.lastIdx)
+	b.mu.Unlock()
+}
+
+// rehighlight evaluates the history against changed highlight rules, so
+// clients connecting later get the new styles.
+func (b *broker) rehighlight() {
+	b.mu.Lock()
+	for i := range b.history {
+		b.history[i] = highlightMsg(b.history[i])
+	}
 	b.mu.Unlock()
 }
 
@@ -106,11 +260,38 @@ func sendMsg(w http.ResponseWriter, flusher http.Flusher, msg logMsg) {
 }
 
 func restartApp() {
+	execSelf(restartCommand())
+}
+
+// restartCommand is the command line and environment restartApp execs.
+func restartCommand() ([]string, []string) {
+	return os.Args, append(os.Environ(), restoreEnv+"=1")
+}
+
+// switchWorkspace replaces the process with one started on workspace name,
+// keeping the other flags.
+func switchWorkspace(name string) {
+	execSelf(workspaceCommand(name))
+}
+
+// workspaceCommand is the command line and environment switchWorkspace execs.
+func workspaceCommand(name string) ([]string, []string) {
+	args := []string{os.Args[0]}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "w" {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return append(args, "-w", name), os.Environ()
+}
+
+// execSelf replaces the process with this executable run with args and env.
+func execSelf(args, env []string) {
 	exe, err := os.Executable()
 	if err != nil {
 		exe = os.Args[0]
 	}
-	syscall.Exec(exe, os.Args, os.Environ()) //nolint:errcheck
+	syscall.Exec(exe, args, env) //nolint:errcheck
 }
 
 // stdinIsPiped reports whether stdin is a pipe (not a terminal).
@@ -122,36 +303,123 @@ func stdinIsPiped() bool {
 	return (fi.Mode() & os.ModeCharDevice) == 0
 }
 
-func serveHTML() string {
+// serveHTML returns the UI page with the theme applied and the token its
+// fetches send in tokenHeader.
+func serveHTML(token string) string {
 	prefsMu.Lock()
 	theme := curPrefs.Theme
 	prefsMu.Unlock()
+	page := strings.Replace(htmlContent, `<meta charset="UTF-8">`,
+		`<meta charset="UTF-8">`+"\n  "+`<meta name="jsonlv-token" content="`+html.EscapeString(token)+`">`, 1)
 	if theme == "light" || theme == "solarized" {
-		return strings.Replace(htmlContent, "<body>", `<body class="`+theme+`">`, 1)
+		return strings.Replace(page, "<body>", `<body class="`+theme+`">`, 1)
 	}
-	return htmlContent
+	return page
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cat" {
+		os.Exit(runCat(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "remote" {
+		os.Exit(runRemote(os.Args[2:], os.Stderr))
+	}
+
 	initMappings()
+	initBookmarks()
 
 	prefs := loadPrefs()
 	prefsMu.Lock()
 	curPrefs = prefs
 	prefsMu.Unlock()
+	if err := setRedaction(prefs.redaction()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: redaction: %v\n", err)
+		setRedaction(defaultRedaction) //nolint:errcheck
+	}
+	if err := setHighlights(prefs.Highlights); err != nil {
+		fmt.Fprintf(os.Stderr, "error: highlights: %v\n", err)
+	}
 
 	follow := flag.Bool("f", false, "follow file(s) for new lines")
 	lines := flag.Int("n", 1000, "number of lines from end of file")
 	headless := flag.Bool("headless", false, "HTTP-only mode for testing (no GUI)")
+	browser := flag.Bool("browser", false, "show the UI in the default browser instead of a window")
+	tuiMode := flag.Bool("tui", false, "show the log stream in the terminal instead of a window")
 	listenPort := flag.Int("port", 0, "HTTP listen port (0 = random)")
+	sinceArg := flag.String("since", "", "load lines at or after this time instead of the tail")
+	untilArg := flag.String("until", "", "load lines up to this time instead of the tail")
+	dedup := flag.Bool("dedup", false, "fold consecutive duplicate entries per source")
+	workspaceArg := flag.String("w", "", "open the named workspace")
+	newInstance := flag.Bool("new", false, "open a new window even if one is running")
 	flag.Parse()
 	files := flag.Args()
 
+	since, err := parseTimeArg(*sinceArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: -since: %v\n", err)
+		os.Exit(2)
+	}
+	until, err := parseTimeArg(*untilArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: -until: %v\n", err)
+		os.Exit(2)
+	}
+	timeRange := !since.IsZero() || !until.IsZero()
+
+	// Like `code file.txt`: files go to the window that is already open.
+	windowed := !*headless && !*browser && !*tuiMode
+	if windowed && len(files) > 0 && !*newInstance && !timeRange && *workspaceArg == "" && os.Getenv(restoreEnv) == "" {
+		abs, err := absPaths(files)
+		if err == nil {
+			err = sendControl(controlRequest{Cmd: "open", Args: abs})
+		}
+		if err == nil {
+			os.Exit(0)
+		}
+		if !errors.Is(err, errNoInstance) {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	b := newBroker()
+	b.setDedup(*dedup || prefs.Dedup)
 	w := NewWatcher(b)
+	miner := b.patterns
+	traces := newTraceIndex(prefs.CorrelationKeys)
+	go traces.run(b)
 
 	piped := stdinIsPiped()
 
+	// After a restart, the files and view saved just before come back.
+	var restored *session
+	if os.Getenv(restoreEnv) != "" {
+		os.Unsetenv(restoreEnv) //nolint:errcheck
+		if s, err := loadSession(restartSessionPath()); err == nil {
+			restored = &s
+			for _, src := range s.Sources {
+				files = slices.DeleteFunc(files, func(f string) bool { return f == src.Path })
+			}
+			openSession(w, s)
+		}
+	}
+
+	if *workspaceArg != "" {
+		ws, err := loadWorkspace(*workspaceArg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: workspace %s: %v\n", *workspaceArg, err)
+			os.Exit(2)
+		}
+		setActiveWorkspace(ws.Name)
+		// A restart brings back the session, which already holds the workspace.
+		if restored == nil {
+			s := ws.session()
+			restored = &s
+			files = slices.DeleteFunc(files, func(f string) bool { return slices.Contains(ws.Files, f) })
+			openSession(w, s)
+		}
+	}
+
 	if len(files) == 0 && piped {
 		// Read from stdin
 		go func() {
@@ -163,31 +431,57 @@ func main() {
 		}()
 	} else {
 		for _, path := range files {
-			w.Register(path)
+			stop := w.Register(path)
 			source := filepath.Base(path)
 			go func() {
-				tail, err := lastNLines(path, *lines)
+				var tail []string
+				var offsets []int64
+				var err error
+				if timeRange {
+					tail, offsets, err = readTimeRangeAt(path, since, until, maxHistory)
+				} else {
+					tail, offsets, err = lastNLinesAt(path, *lines)
+				}
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
 					return
 				}
-				for _, line := range tail {
+				for i, line := range tail {
+					select {
+					case <-stop:
+						return // closed meanwhile
+					default:
+					}
 					if line != "" {
-						b.publish(source, line)
+						b.publishAt(path, source, line, offsets[i])
 					}
 				}
-				if *follow {
-					followFile(path, source, b)
+				if *follow && until.IsZero() {
+					followFile(path, -1, stop, func(line string, off int64) { b.publishAt(path, source, line, off) })
 				}
 			}()
 		}
 	}
 
+	if *tuiMode {
+		if err := runTUI(b); err != nil {
+			fmt.Fprintf(os.Stderr, "error: -tui: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	auth := serverAuth{token: launchToken()}
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Unknown paths are 404 so the UI can tell which endpoints a mode has.
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, serveHTML())
+		fmt.Fprint(w, serveHTML(auth.token))
 	})
 
 	mux.HandleFunc("/prefs", func(w http.ResponseWriter, r *http.Request) {
@@ -199,6 +493,10 @@ func main() {
 	})
 
 	mux.HandleFunc("/set-col-width", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 		var req struct {
 			Key   string   `json:"key"`
 			Width *float64 `json:"width"`
@@ -222,6 +520,10 @@ func main() {
 	})
 
 	mux.HandleFunc("/set-theme", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 		body, _ := io.ReadAll(r.Body)
 		theme := strings.TrimSpace(string(body))
 		if theme == "dark" || theme == "light" || theme == "solarized" {
@@ -230,12 +532,550 @@ func main() {
 		w.WriteHeader(http.StatusNoContent)
 	})
 
+	// In a workspace the view belongs to the workspace, otherwise to the set
+	// of open files.
+	mux.HandleFunc("/view", func(rw http.ResponseWriter, r *http.Request) {
+		name := currentWorkspace()
+		key := viewKey(w.Files())
+		if name != "" {
+			key = "workspace:" + name
+		}
+		if r.Method == http.MethodPost {
+			var v viewPrefs
+			if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			if name == "" {
+				setViewPref(key, v)
+			} else if err := setWorkspaceView(name, v); err != nil {
+				fmt.Fprintf(os.Stderr, "error: workspace %s: %v\n", name, err)
+			}
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var v viewPrefs
+		if name == "" {
+			v, _ = viewPref(key)
+		} else if ws, err := loadWorkspace(name); err == nil {
+			v = viewPrefs{CustomColumns: ws.View.CustomColumns, PropertyFilters: ws.View.PropertyFilters}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]any{"key": key, "view": v}) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/workspaces", func(rw http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		switch r.Method {
+		case http.MethodPost:
+			var view sessionView
+			if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			prefsMu.Lock()
+			widths := maps.Clone(curPrefs.ColumnWidths)
+			prefsMu.Unlock()
+			ws := workspaceFromView(name, w.Files(), view, widths)
+			if err := saveWorkspace(ws); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if currentWorkspace() == "" {
+				setActiveWorkspace(ws.Name)
+			}
+		case http.MethodDelete:
+			if err := deleteWorkspace(name); err != nil {
+				http.Error(rw, err.Error(), http.StatusNotFound)
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			select {
+			case menuFileCh <- "workspaces":
+			default:
+			}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]any{ //nolint:errcheck
+			"active": currentWorkspace(),
+			"names":  listWorkspaces(),
+		})
+	})
+
+	mux.HandleFunc("/mappings", func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut:
+			var req struct {
+				Old string `json:"old"` // PUT: remote of the rule being edited
+				mappingRule
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			if err := putMapping(req.Old, req.mappingRule); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			if !deleteMapping(r.URL.Query().Get("remote")) {
+				http.Error(rw, "unknown mapping", http.StatusNotFound)
+				return
+			}
+		}
+		sets, active := mappingSetNames()
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]any{ //nolint:errcheck
+			"active":   active,
+			"sets":     sets,
+			"mappings": listMappings(),
+		})
+	})
+
+	// POST moves the rule ?remote= one place up (?dir=-1) or down (?dir=1).
+	mux.HandleFunc("/mappings/move", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		dir, _ := strconv.Atoi(r.URL.Query().Get("dir"))
+		if !moveMapping(r.URL.Query().Get("remote"), dir) {
+			http.Error(rw, "unknown mapping", http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	// POST switches to (and creates) the set ?name=, DELETE removes it.
+	mux.HandleFunc("/mappings/set", func(rw http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		var err error
+		switch r.Method {
+		case http.MethodPost:
+			err = useMappingSet(name)
+		case http.MethodDelete:
+			err = deleteMappingSet(name)
+		default:
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	// POST learns a prefix mapping from one remote file and the local file
+	// the user picked for it, like /pick-file does after the native picker.
+	mux.HandleFunc("/mappings/learn", func(rw http.ResponseWriter, r *http.Request) {
+		remote, local := r.URL.Query().Get("remote"), r.URL.Query().Get("local")
+		if r.Method != http.MethodPost || remote == "" || local == "" {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		addMapping(remote, local)
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/mappings/test", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(testMapping(r.URL.Query().Get("path"))) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/editors", func(rw http.ResponseWriter, r *http.Request) {
+		prefsMu.Lock()
+		profiles := editorProfiles(curPrefs.Editors)
+		active := findEditor(profiles, curPrefs.Editor).Name
+		prefsMu.Unlock()
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]any{"active": active, "profiles": profiles}) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/set-editor", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		name := strings.TrimSpace(string(body))
+		prefsMu.Lock()
+		profiles := editorProfiles(curPrefs.Editors)
+		prefsMu.Unlock()
+		if findEditor(profiles, name).Name != name {
+			http.Error(rw, "unknown editor", http.StatusBadRequest)
+			return
+		}
+		setEditorPref(name)
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/set-dedup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		on := strings.TrimSpace(string(body)) == "on"
+		setDedupPref(on)
+		b.setDedup(on)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/range", func(rw http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		path, ok := w.Path(q.Get("source"))
+		if !ok {
+			http.Error(rw, "unknown source", http.StatusNotFound)
+			return
+		}
+		from, err := parseTimeArg(q.Get("from"))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := parseTimeArg(q.Get("to"))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lines, offsets, err := readTimeRangeAt(path, from, to, maxHistory)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		source := filepath.Base(path)
+		msgs := make([]logMsg, len(lines))
+		for i, line := range lines {
+			msgs[i] = highlightMsg(redactMsg(logMsg{S: source, D: line, O: &offsets[i]}))
+			msgs[i].M = miner.match(parseEntry(source, msgs[i].D))
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(msgs) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/lines", func(rw http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		path, ok := w.Path(q.Get("source"))
+		if !ok {
+			http.Error(rw, "unknown source", http.StatusNotFound)
+			return
+		}
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		limit := 500
+		if v := q.Get("limit"); v != "" {
+			limit, _ = strconv.Atoi(v)
+		}
+		if offset < 0 || limit < 0 || limit > maxPageLines {
+			http.Error(rw, "bad offset or limit", http.StatusBadRequest)
+			return
+		}
+		li := lineIndexFor(path)
+		total, lines, err := li.readLines(offset, limit)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		source := filepath.Base(path)
+		starts := li.lineStarts(offset, len(lines))
+		msgs := make([]logMsg, len(lines))
+		for i, line := range lines {
+			msgs[i] = highlightMsg(redactMsg(logMsg{S: source, D: line, O: &starts[i]}))
+			msgs[i].M = miner.match(parseEntry(source, msgs[i].D))
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]any{ //nolint:errcheck
+			"total":  total,
+			"offset": offset,
+			"lines":  msgs,
+		})
+	})
+
+	mux.HandleFunc("/agg", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opt := aggOptions{By: q.Get("by"), Top: 10, Stats: []string{"duration_ms"}}
+		if v := q.Get("interval"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, "bad interval", http.StatusBadRequest)
+				return
+			}
+			opt.Interval = d
+		}
+		if v := q.Get("buckets"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "bad buckets", http.StatusBadRequest)
+				return
+			}
+			opt.Buckets = n
+		}
+		if v := q.Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "bad top", http.StatusBadRequest)
+				return
+			}
+			opt.Top = n
+		}
+		if v, ok := q["stats"]; ok {
+			opt.Stats = strings.FieldsFunc(strings.Join(v, ","), func(r rune) bool { return r == ',' })
+		}
+		where, err := parseQuery(q.Get("where"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opt.Where = where
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(aggregate(b.snapshot(), opt)) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/patterns", func(w http.ResponseWriter, r *http.Request) {
+		patterns := miner.list()
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, _ = strconv.Atoi(v)
+		}
+		if limit > 0 && len(patterns) > limit {
+			patterns = patterns[:limit]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(patterns) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "jsonl"
+		}
+		ft, ok := exportFormats[format]
+		if !ok {
+			http.Error(w, "unknown format", http.StatusBadRequest)
+			return
+		}
+		f, err := parseExportFilter(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", ft[1])
+		w.Header().Set("Content-Disposition", `attachment; filename="jsonlv-export.`+ft[0]+`"`)
+		cols := strings.FieldsFunc(q.Get("cols"), func(r rune) bool { return r == ',' })
+		writeExport(w, b.snapshot(), f, format, cols, bookmarkNotes()) //nolint:errcheck
+	})
+
+	// currentSession combines the view the UI posts with the open files.
+	currentSession := func(r *http.Request) (session, error) {
+		var view sessionView
+		if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+			return session{}, err
+		}
+		prefsMu.Lock()
+		widths := curPrefs.ColumnWidths
+		prefsMu.Unlock()
+		return session{
+			Version:      sessionVersion,
+			Sources:      w.sessionSources(view.OlderLines),
+			View:         view,
+			ColumnWidths: widths,
+			Bookmarks:    listBookmarks(),
+		}, nil
+	}
+
+	mux.HandleFunc("/session", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost {
+			json.NewEncoder(rw).Encode(takePendingSession()) //nolint:errcheck
+			return
+		}
+		s, err := currentSession(r)
+		if err != nil {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(rw).Encode(s) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/session/open", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var s session
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		openSession(w, s)
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/bookmarks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(listBookmarks()) //nolint:errcheck
+			return
+		}
+		var bm bookmark
+		if err := json.NewDecoder(r.Body).Decode(&bm); err != nil || bm.Line == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(setBookmark(bm)) //nolint:errcheck
+		case http.MethodDelete:
+			if !removeBookmark(bm.Source, bm.Offset, bm.Line) {
+				http.Error(w, "no such bookmark", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/trace", func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		entries := traces.lookup(id)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+			"id":      id,
+			"entries": entries,
+			"spans":   buildSpanTree(entries),
+		})
+	})
+
+	// POST with a raw log line returns the stack traces found in it.
+	mux.HandleFunc("/stack", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		line, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(extractStackTraces(parseEntry("", string(line)))) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/set-correlation-keys", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var keys []string
+		if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if len(keys) == 0 {
+			keys = defaultCorrelationKeys
+		}
+		setCorrelationKeysPref(keys)
+		traces.setKeys(keys, b.snapshot())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/redaction", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var p redactionPrefs
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			if err := setRedaction(p); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			setRedactionPref(p)
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		prefsMu.Lock()
+		p := curPrefs.redaction()
+		prefsMu.Unlock()
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]any{ //nolint:errcheck
+			"rules":     p,
+			"detectors": detectorNames(),
+		})
+	})
+
+	mux.HandleFunc("/highlights", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var rules []highlightRule
+			if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			if err := setHighlights(rules); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			setHighlightsPref(rules)
+			b.rehighlight()
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		prefsMu.Lock()
+		rules := curPrefs.Highlights
+		prefsMu.Unlock()
+		if rules == nil {
+			rules = []highlightRule{}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(rules) //nolint:errcheck
+	})
+
+	// /highlights/match evaluates the current rules for the posted entries,
+	// for the rows already shown when the rules change.
+	mux.HandleFunc("/highlights/match", func(rw http.ResponseWriter, r *http.Request) {
+		var msgs []logMsg
+		if err := json.NewDecoder(r.Body).Decode(&msgs); err != nil {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		matches := make([]int, len(msgs))
+		for i, m := range msgs {
+			matches[i] = highlightMsg(m).H
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(matches) //nolint:errcheck
+	})
+
+	// /reveal returns the unmasked line of a redacted entry; POST, so only the
+	// UI itself can ask (see auth.go).
+	mux.HandleFunc("/reveal", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, _ := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		line, err := revealOriginal(id)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(rw, line)
+	})
+
 	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
 		flusher := w.(http.Flusher)
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		hist, ch := b.subscribe()
 		defer b.unsubscribe(ch)
@@ -266,6 +1106,10 @@ func main() {
 
 	if *headless {
 		mux.HandleFunc("/inject", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
 			body, _ := io.ReadAll(r.Body)
 			src := r.URL.Query().Get("src")
 			if src == "" {
@@ -275,7 +1119,13 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 		})
 		mux.HandleFunc("/reset", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
 			b.reset()
+			miner.reset()
+			traces.reset()
 			w.WriteHeader(http.StatusNoContent)
 		})
 	}
@@ -284,22 +1134,22 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	port := ln.Addr().(*net.TCPAddr).Port
-	go http.Serve(ln, mux) //nolint:errcheck
+	auth.port = ln.Addr().(*net.TCPAddr).Port
+	go http.Serve(ln, auth.wrap(mux)) //nolint:errcheck
 
 	if *headless {
-		fmt.Printf("http://127.0.0.1:%d\n", port)
+		fmt.Println(auth.launchURL())
 		select {}
 	}
 
-	wv := webview.New(true)
-	defer wv.Destroy()
-	setupAppMenu()
-	setupAppIcon()
-
 	mux.HandleFunc("/open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 		file := r.URL.Query().Get("file")
 		line := r.URL.Query().Get("line")
+		column := r.URL.Query().Get("column")
 		if file == "" {
 			http.Error(w, "missing file", http.StatusBadRequest)
 			return
@@ -310,18 +1160,147 @@ func main() {
 			json.NewEncoder(w).Encode(map[string]string{"status": "not_found", "remote": file}) //nolint:errcheck
 			return
 		}
-		psURL := "phpstorm://open?file=" + url.QueryEscape(local)
-		if line != "" {
-			psURL += "&line=" + line
+		if err := openInEditor(local, line, column); err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"status": "error", "error": err.Error()}) //nolint:errcheck
+			return
 		}
-		exec.Command("open", psURL).Start() //nolint:errcheck
 		json.NewEncoder(w).Encode(map[string]string{"status": "ok"}) //nolint:errcheck
 	})
 
+	// The browser has no native pickers: a dialog in the page browses the
+	// local file system through /fs and opens files with /files/open.
+	if *browser {
+		mux.HandleFunc("/fs", func(rw http.ResponseWriter, r *http.Request) {
+			path := r.URL.Query().Get("path")
+			if path == "" {
+				path = defaultBrowseDir(loadRecent())
+			}
+			l, err := listDir(path, r.URL.Query().Get("hidden") == "1")
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(rw).Encode(l) //nolint:errcheck
+		})
+		mux.HandleFunc("/recent", func(rw http.ResponseWriter, r *http.Request) {
+			recent := loadRecent()
+			if r.Method == http.MethodDelete {
+				clearRecent()
+				recent = nil
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(rw).Encode(append([]string{}, recent...)) //nolint:errcheck
+		})
+		mux.HandleFunc("/files/open", func(rw http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Paths []string `json:"paths"`
+			}
+			if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil || len(req.Paths) == 0 {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			for _, p := range req.Paths {
+				if fi, err := os.Stat(p); err != nil || fi.IsDir() {
+					http.Error(rw, "not a file: "+p, http.StatusBadRequest)
+					return
+				}
+			}
+			for _, p := range req.Paths {
+				w.Add(p)
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(rw).Encode(addRecent(req.Paths)) //nolint:errcheck
+		})
+
+		// The rest of the window's File menu. Restarting and switching
+		// workspaces replace the process on the same port and token, and the
+		// page reloads once it answers again.
+		reexec := func(args, env []string) {
+			time.Sleep(100 * time.Millisecond) // let the response go out
+			execSelf(browserCommand(args, env, auth.port, auth.token))
+		}
+		mux.HandleFunc("/session/load", func(rw http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Path string `json:"path"`
+			}
+			if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil || req.Path == "" {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			s, err := loadSession(req.Path)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			openSession(w, s)
+			rw.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("/files/clear", func(rw http.ResponseWriter, r *http.Request) {
+			files := w.Files()
+			if r.Method == http.MethodPost {
+				for _, path := range files {
+					os.Truncate(path, 0) //nolint:errcheck
+				}
+			}
+			slices.Sort(files)
+			rw.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(rw).Encode(append([]string{}, files...)) //nolint:errcheck
+		})
+		mux.HandleFunc("/session/restart", func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			s, err := currentSession(r)
+			if err != nil {
+				http.Error(rw, "bad request", http.StatusBadRequest)
+				return
+			}
+			if err := saveSession(restartSessionPath(), s); err != nil {
+				fmt.Fprintf(os.Stderr, "error: saving session: %v\n", err)
+			}
+			rw.WriteHeader(http.StatusNoContent)
+			go reexec(restartCommand())
+		})
+		mux.HandleFunc("/workspaces/switch", func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			name := r.URL.Query().Get("name")
+			if _, err := loadWorkspace(name); err != nil {
+				http.Error(rw, "unknown workspace", http.StatusNotFound)
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+			go reexec(workspaceCommand(name))
+		})
+
+		url := auth.launchURL()
+		fmt.Println(url)
+		if os.Getenv(browserReloadEnv) != "" {
+			os.Unsetenv(browserReloadEnv) //nolint:errcheck
+		} else if err := openURL(url); err != nil {
+			fmt.Fprintf(os.Stderr, "error: opening browser: %v\n", err)
+		}
+		select {}
+	}
+
+	wv := webview.New(true)
+	defer wv.Destroy()
+	shell := newNativeShell()
+	shell.SetupAppMenu(wv.Window())
+	shell.SetupAppIcon(wv.Window())
+
 	mux.HandleFunc("/pick-file", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 		remote := r.URL.Query().Get("remote")
 		result := make(chan string, 1)
-		wv.Dispatch(func() { result <- PickLocalFile() })
+		wv.Dispatch(func() { result <- shell.PickLocalFile() })
 		local := <-result
 		if local == "" {
 			w.WriteHeader(499) // user cancelled
@@ -334,15 +1313,153 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "local": local}) //nolint:errcheck
 	})
 
+	mux.HandleFunc("/export-save", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		ft, ok := exportFormats[q.Get("format")]
+		if !ok {
+			http.Error(w, "unknown format", http.StatusBadRequest)
+			return
+		}
+		f, err := parseExportFilter(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := make(chan string, 1)
+		wv.Dispatch(func() { result <- shell.PickSaveFile("jsonlv-export." + ft[0]) })
+		path := <-result
+		if path == "" {
+			w.WriteHeader(499) // user cancelled
+			return
+		}
+		out, err := os.Create(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer out.Close()
+		cols := strings.FieldsFunc(q.Get("cols"), func(r rune) bool { return r == ',' })
+		if err := writeExport(out, b.snapshot(), f, q.Get("format"), cols, bookmarkNotes()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "path": path}) //nolint:errcheck
+	})
+
+	mux.HandleFunc("/session/save", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s, err := currentSession(r)
+		if err != nil {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		result := make(chan string, 1)
+		wv.Dispatch(func() { result <- shell.PickSaveFile("jsonlv-session.json") })
+		path := <-result
+		if path == "" {
+			rw.WriteHeader(499) // user cancelled
+			return
+		}
+		if err := saveSession(path, s); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	restartReady := make(chan struct{}, 1)
+	mux.HandleFunc("/session/restart", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s, err := currentSession(r)
+		if err != nil {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		if err := saveSession(restartSessionPath(), s); err != nil {
+			fmt.Fprintf(os.Stderr, "error: saving session: %v\n", err)
+		}
+		rw.WriteHeader(http.StatusNoContent)
+		select {
+		case restartReady <- struct{}{}:
+		default:
+		}
+	})
+
+	mux.HandleFunc("/workspaces/switch", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if _, err := loadWorkspace(name); err != nil {
+			http.Error(rw, "unknown workspace", http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+		menuFileCh <- "workspace:" + name
+	})
+
 	recent := loadRecent()
-	SetupFileMenu(recent)
+	shell.SetupFileMenu(recent)
+	shell.RebuildWorkspaceMenu(listWorkspaces(), currentWorkspace())
+
+	saveWindowFrame := func() {
+		ch := make(chan [4]float64, 1)
+		wv.Dispatch(func() {
+			x, y, w, h := shell.GetWindowFrame(wv.Window())
+			ch <- [4]float64{x, y, w, h}
+		})
+		frame := <-ch
+		setWindowPref(frame[0], frame[1], frame[2], frame[3])
+	}
+
+	// Later invocations and `jsonlv remote` reach this window through the
+	// control socket; with -new another window may already own it.
+	if ln, err := listenControl(); err == nil {
+		go serveControl(ln, func(action string) { menuFileCh <- action })
+	} else if !errors.Is(err, errInstanceRunning) {
+		fmt.Fprintf(os.Stderr, "error: control socket: %v\n", err)
+	}
 
 	go func() {
 		for action := range menuFileCh {
+			if name, ok := strings.CutPrefix(action, "workspace:"); ok {
+				saveWindowFrame()
+				switchWorkspace(name)
+				continue
+			}
+			if path, ok := strings.CutPrefix(action, "close:"); ok {
+				if w.Remove(path) {
+					// The UI tells entries apart by basename only, so its rows
+					// stay while another open file shares it; a reload shows
+					// the rest.
+					source := filepath.Base(path)
+					if _, shared := w.Path(source); !shared {
+						js, _ := json.Marshal(source)
+						wv.Dispatch(func() { wv.Eval("closeSource(" + string(js) + ")") })
+					}
+				}
+				continue
+			}
+			if view, ok := strings.CutPrefix(action, "filter:"); ok {
+				wv.Dispatch(func() { wv.Eval("applyRemoteFilter(" + view + ")") })
+				continue
+			}
 			switch action {
 			case "open":
 				result := make(chan []string, 1)
-				wv.Dispatch(func() { result <- PickMultipleFiles() })
+				wv.Dispatch(func() { result <- shell.PickMultipleFiles() })
 				paths := <-result
 				if len(paths) == 0 {
 					continue
@@ -351,17 +1468,42 @@ func main() {
 					w.Add(p)
 				}
 				recent := addRecent(paths)
-				wv.Dispatch(func() { RebuildRecentMenu(recent) })
+				wv.Dispatch(func() { shell.RebuildRecentMenu(recent) })
+			case "export":
+				wv.Dispatch(func() { wv.Eval("openExportDialog()") })
+			case "save-session":
+				wv.Dispatch(func() { wv.Eval("saveSessionAs()") })
+			case "open-session":
+				result := make(chan string, 1)
+				wv.Dispatch(func() { result <- shell.PickLocalFile() })
+				path := <-result
+				if path == "" {
+					continue
+				}
+				s, err := loadSession(path)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+					continue
+				}
+				openSession(w, s)
+				wv.Dispatch(func() { wv.Eval("restoreSession()") })
+			case "workspaces":
+				names, active := listWorkspaces(), currentWorkspace()
+				wv.Dispatch(func() { shell.RebuildWorkspaceMenu(names, active) })
+			case "manage-workspaces":
+				wv.Dispatch(func() { wv.Eval("openWorkspaceDialog()") })
+			case "clear-view":
+				wv.Dispatch(func() { wv.Eval("document.getElementById('clear-btn').click()") })
 			case "clear":
 				clearRecent()
-				wv.Dispatch(func() { RebuildRecentMenu(nil) })
+				wv.Dispatch(func() { shell.RebuildRecentMenu(nil) })
 			case "clear-log-files":
 				files := w.Files()
 				if len(files) == 0 {
 					continue
 				}
 				result := make(chan bool, 1)
-				wv.Dispatch(func() { result <- ShowClearLogFilesAlert(files) })
+				wv.Dispatch(func() { result <- shell.ShowClearLogFilesAlert(files) })
 				if !<-result {
 					continue
 				}
@@ -369,18 +1511,19 @@ func main() {
 					os.Truncate(path, 0) //nolint:errcheck
 				}
 			case "restart":
-				ch := make(chan [4]float64, 1)
-				wv.Dispatch(func() {
-					x, y, w, h := GetWindowFrame(wv.Window())
-					ch <- [4]float64{x, y, w, h}
-				})
-				frame := <-ch
-				setWindowPref(frame[0], frame[1], frame[2], frame[3])
+				// Let the UI save its view; restart regardless if it does not answer.
+				os.Remove(restartSessionPath()) //nolint:errcheck
+				wv.Dispatch(func() { wv.Eval("saveSessionForRestart()") })
+				select {
+				case <-restartReady:
+				case <-time.After(2 * time.Second):
+				}
+				saveWindowFrame()
 				restartApp()
 			default:
 				w.Add(action)
 				recent := addRecent([]string{action})
-				wv.Dispatch(func() { RebuildRecentMenu(recent) })
+				wv.Dispatch(func() { shell.RebuildRecentMenu(recent) })
 			}
 		}
 	}()
@@ -395,30 +1538,30 @@ func main() {
 	wv.SetSize(int(savedW), int(savedH), webview.HintNone)
 
 	wv.Bind("nativeQuit", func() { //nolint:errcheck
-		x, y, w, h := GetWindowFrame(wv.Window())
+		x, y, w, h := shell.GetWindowFrame(wv.Window())
 		setWindowPref(x, y, w, h)
 		os.Exit(0)
 	})
 	wv.Bind("nativeOpenURL", func(rawURL string) { //nolint:errcheck
-		exec.Command("open", rawURL).Start() //nolint:errcheck
+		openURL(rawURL) //nolint:errcheck
 	})
-	wv.Navigate(fmt.Sprintf("http://127.0.0.1:%d", port))
+	wv.Navigate(auth.launchURL())
 
 	// Restore saved window position and install quit delegate (window exists after Navigate).
 	wv.Dispatch(func() {
 		winPtr := wv.Window()
 		if prefs.Window.X != 0 || prefs.Window.Y != 0 {
-			SetWindowFrame(winPtr, prefs.Window.X, prefs.Window.Y, savedW, savedH)
+			shell.SetWindowFrame(winPtr, prefs.Window.X, prefs.Window.Y, savedW, savedH)
 		}
-		InstallAppDelegate(winPtr)
+		shell.InstallAppDelegate(winPtr)
 	})
 
 	// Ask to reopen recent files when launched without arguments and not piped.
-	if len(files) == 0 && !piped && len(recent) > 0 {
+	if len(files) == 0 && !piped && len(recent) > 0 && restored == nil {
 		go func() {
 			time.Sleep(400 * time.Millisecond)
 			result := make(chan bool, 1)
-			wv.Dispatch(func() { result <- ShowReopenAlert(len(recent)) })
+			wv.Dispatch(func() { result <- shell.ShowReopenAlert(len(recent)) })
 			if <-result {
 				go w.ReopenSorted(recent)
 			}
@@ -58,12 +58,18 @@ extern void cOpenFile(const char *path);
 extern void cClearRecent(void);
 extern void cRestartApp(void);
 extern void cClearLogFiles(void);
+extern void cExport(void);
+extern void cSaveSession(void);
+extern void cOpenSession(void);
+extern void cSwitchWorkspace(const char *name);
+extern void cManageWorkspaces(void);
 extern void cSaveWindowFrame(CGFloat x, CGFloat y, CGFloat w, CGFloat h);
 
 // ── File menu handler ─────────────────────────────────────────────────────────
 
 @interface JSONLVMenuHandler : NSObject
 @property (nonatomic, copy) NSString *filePath;
+@property (nonatomic, copy) NSString *workspace;
 @end
 @implementation JSONLVMenuHandler
 - (void)doOpen:(id)sender          { cMenuOpenFiles(); }
@@ -71,10 +77,16 @@ extern void cSaveWindowFrame(CGFloat x, CGFloat y, CGFloat w, CGFloat h);
 - (void)doClear:(id)sender         { cClearRecent(); }
 - (void)doRestart:(id)sender       { cRestartApp(); }
 - (void)doTruncateLogs:(id)sender  { cClearLogFiles(); }
+- (void)doExport:(id)sender        { cExport(); }
+- (void)doSaveSession:(id)sender   { cSaveSession(); }
+- (void)doOpenSession:(id)sender   { cOpenSession(); }
+- (void)doSwitchWorkspace:(id)sender { cSwitchWorkspace([self.workspace UTF8String]); }
+- (void)doManageWorkspaces:(id)sender { cManageWorkspaces(); }
 @end
 
-static JSONLVMenuHandler *gMenuHandler = nil;
-static NSMenu            *gRecentMenu  = nil;
+static JSONLVMenuHandler *gMenuHandler    = nil;
+static NSMenu            *gRecentMenu     = nil;
+static NSMenu            *gWorkspaceMenu  = nil;
 
 void rebuildRecentMenuC(const char *recentNL) {
     [gRecentMenu removeAllItems];
@@ -100,6 +112,29 @@ void rebuildRecentMenuC(const char *recentNL) {
     [gRecentMenu addItem:clear];
 }
 
+void rebuildWorkspaceMenuC(const char *namesNL, const char *active) {
+    [gWorkspaceMenu removeAllItems];
+    NSString *joined = namesNL ? [NSString stringWithUTF8String:namesNL] : @"";
+    NSString *current = active ? [NSString stringWithUTF8String:active] : @"";
+    NSArray<NSString*> *names = joined.length > 0
+        ? [joined componentsSeparatedByString:@"\n"] : @[];
+    for (NSString *n in names) {
+        if (!n.length) continue;
+        JSONLVMenuHandler *h = [JSONLVMenuHandler new];
+        h.workspace = n;
+        NSMenuItem *item = [[NSMenuItem alloc]
+            initWithTitle:n action:@selector(doSwitchWorkspace:) keyEquivalent:@""];
+        item.target = h;
+        if ([n isEqualToString:current]) item.state = NSControlStateValueOn;
+        [gWorkspaceMenu addItem:item];
+    }
+    if (names.count > 0) [gWorkspaceMenu addItem:[NSMenuItem separatorItem]];
+    NSMenuItem *manage = [[NSMenuItem alloc]
+        initWithTitle:@"Arbeitsbereiche verwalten…" action:@selector(doManageWorkspaces:) keyEquivalent:@""];
+    manage.target = gMenuHandler;
+    [gWorkspaceMenu addItem:manage];
+}
+
 void setupFileMenu(const char *recentNL) {
     gMenuHandler = [JSONLVMenuHandler new];
 
@@ -123,6 +158,27 @@ void setupFileMenu(const char *recentNL) {
 
     rebuildRecentMenuC(recentNL);
 
+    [fileMenu addItem:[NSMenuItem separatorItem]];
+    NSMenuItem *exportItem = [[NSMenuItem alloc]
+        initWithTitle:@"Exportieren…" action:@selector(doExport:) keyEquivalent:@"e"];
+    exportItem.target = gMenuHandler;
+    [fileMenu addItem:exportItem];
+
+    NSMenuItem *saveSessionItem = [[NSMenuItem alloc]
+        initWithTitle:@"Sitzung speichern…" action:@selector(doSaveSession:) keyEquivalent:@"s"];
+    saveSessionItem.target = gMenuHandler;
+    [fileMenu addItem:saveSessionItem];
+    NSMenuItem *openSessionItem = [[NSMenuItem alloc]
+        initWithTitle:@"Sitzung öffnen…" action:@selector(doOpenSession:) keyEquivalent:@""];
+    openSessionItem.target = gMenuHandler;
+    [fileMenu addItem:openSessionItem];
+
+    NSMenuItem *workspaceItem = [[NSMenuItem alloc]
+        initWithTitle:@"Arbeitsbereich" action:nil keyEquivalent:@""];
+    [fileMenu addItem:workspaceItem];
+    gWorkspaceMenu = [[NSMenu alloc] initWithTitle:@"Arbeitsbereich"];
+    [workspaceItem setSubmenu:gWorkspaceMenu];
+
     [fileMenu addItem:[NSMenuItem separatorItem]];
     NSMenuItem *truncateItem = [[NSMenuItem alloc]
         initWithTitle:@"Log-Dateien leeren…" action:@selector(doTruncateLogs:) keyEquivalent:@""];
@@ -230,6 +286,16 @@ char* openFilePicker(void) {
     return NULL;
 }
 
+char* saveFilePicker(const char *name) {
+    NSSavePanel *panel = [NSSavePanel savePanel];
+    panel.title = @"Exportieren";
+    panel.nameFieldStringValue = [NSString stringWithUTF8String:name];
+    if ([panel runModal] == NSModalResponseOK) {
+        return strdup([panel.URL.path UTF8String]);
+    }
+    return NULL;
+}
+
 void setupAppMenu() {
     NSMenu *menubar = [NSMenu new];
     [NSApp setMainMenu:menubar];
@@ -273,10 +339,15 @@ import (
 	"unsafe"
 )
 
-func setupAppMenu() { C.setupAppMenu() }
-func setupAppIcon()  { C.setupAppIcon() }
+// cocoaShell is the macOS nativeShell.
+type cocoaShell struct{}
 
-func PickLocalFile() string {
+func newNativeShell() nativeShell { return cocoaShell{} }
+
+func (cocoaShell) SetupAppMenu(unsafe.Pointer) { C.setupAppMenu() }
+func (cocoaShell) SetupAppIcon(unsafe.Pointer) { C.setupAppIcon() }
+
+func (cocoaShell) PickLocalFile() string {
 	p := C.openFilePicker()
 	if p == nil {
 		return ""
@@ -285,7 +356,7 @@ func PickLocalFile() string {
 	return C.GoString(p)
 }
 
-func PickMultipleFiles() []string {
+func (cocoaShell) PickMultipleFiles() []string {
 	p := C.openMultipleFilesPicker()
 	if p == nil {
 		return nil
@@ -298,37 +369,56 @@ func PickMultipleFiles() []string {
 	return strings.Split(s, "\n")
 }
 
-func SetupFileMenu(recent []string) {
+func (cocoaShell) PickSaveFile(name string) string {
+	cs := C.CString(name)
+	defer C.free(unsafe.Pointer(cs))
+	p := C.saveFilePicker(cs)
+	if p == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(p))
+	return C.GoString(p)
+}
+
+func (cocoaShell) SetupFileMenu(recent []string) {
 	cs := C.CString(strings.Join(recent, "\n"))
 	defer C.free(unsafe.Pointer(cs))
 	C.setupFileMenu(cs)
 }
 
-func RebuildRecentMenu(recent []string) {
+func (cocoaShell) RebuildRecentMenu(recent []string) {
 	cs := C.CString(strings.Join(recent, "\n"))
 	defer C.free(unsafe.Pointer(cs))
 	C.rebuildRecentMenuC(cs)
 }
 
-func InstallAppDelegate(winPtr unsafe.Pointer) {
+func (cocoaShell) RebuildWorkspaceMenu(names []string, active string) {
+	cs := C.CString(strings.Join(names, "\n"))
+	defer C.free(unsafe.Pointer(cs))
+	ca := C.CString(active)
+	defer C.free(unsafe.Pointer(ca))
+	C.rebuildWorkspaceMenuC(cs, ca)
+}
+
+func (cocoaShell) InstallAppDelegate(winPtr unsafe.Pointer) {
 	C.installAppDelegate(winPtr)
 }
 
-func GetWindowFrame(winPtr unsafe.Pointer) (x, y, w, h float64) {
+func (cocoaShell) GetWindowFrame(winPtr unsafe.Pointer) (x, y, w, h float64) {
 	var cx, cy, cw, ch C.CGFloat
 	C.getWindowFrame(winPtr, &cx, &cy, &cw, &ch)
 	return float64(cx), float64(cy), float64(cw), float64(ch)
 }
 
-func SetWindowFrame(winPtr unsafe.Pointer, x, y, w, h float64) {
+func (cocoaShell) SetWindowFrame(winPtr unsafe.Pointer, x, y, w, h float64) {
 	C.setWindowFrame(winPtr, C.CGFloat(x), C.CGFloat(y), C.CGFloat(w), C.CGFloat(h))
 }
 
-func ShowReopenAlert(count int) bool {
+func (cocoaShell) ShowReopenAlert(count int) bool {
 	return C.showReopenAlert(C.int(count)) == 1
 }
 
-func ShowClearLogFilesAlert(files []string) bool {
+func (cocoaShell) ShowClearLogFilesAlert(files []string) bool {
 	cs := C.CString(strings.Join(files, "\n"))
 	defer C.free(unsafe.Pointer(cs))
 	return C.showClearLogFilesAlertC(cs) == 1
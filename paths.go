@@ -2,48 +2,242 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 )
 
+// defaultMappingSet holds the mappings of users who never created a set.
+const defaultMappingSet = "default"
+
+// A mappingRule maps remote paths to local ones. Plain rules (Type "") map a
+// path prefix or one exact file; "glob" and "regex" rules are patterns:
+//
+//	{"remote": "/var/www/html", "local": "/Users/me/shop"}
+//	{"remote": "/home/*/app", "local": "/Users/me/${1}", "type": "glob"}
+//	{"remote": "^/srv/releases/\\d+/", "local": "/Users/me/app/", "type": "regex"}
+//
+// A glob matches a leading part of the path, * within one segment and ** across
+// segments; the rest of the path is appended to Local, which may refer to the
+// wildcards as ${1}, ${2}…. A regex replaces its first match with Local,
+// expanded like regexp.Expand.
+type mappingRule struct {
+	Remote string `json:"remote"`
+	Local  string `json:"local"`
+	Type   string `json:"type,omitempty"`
+
+	re *regexp.Regexp // compiled glob or regex
+}
+
+// A mappingTable resolves paths with one set of rules, deterministically:
+// an exact file mapping wins, then the patterns in their order, then the
+// longest matching prefix.
+type mappingTable struct {
+	exact    map[string]mappingRule
+	patterns []mappingRule
+	prefixes []mappingRule // longest Remote first
+}
+
+// Path mappings are kept in named sets, one per project, of which one is
+// active; activeTable is the compiled active set.
 var (
-	mappingMu sync.RWMutex
-	prefixMap = map[string]string{} // remote prefix → local prefix
+	mappingMu        sync.RWMutex
+	mappingSets      = map[string][]mappingRule{defaultMappingSet: nil}
+	activeMappingSet = defaultMappingSet
+	activeTable      = compileMappings(nil)
 )
 
+// mappingsData is the format of mappings.json.
+type mappingsData struct {
+	Active string                   `json:"active"`
+	Sets   map[string][]mappingRule `json:"sets"`
+}
+
+// mappingSetJSON decodes one set, accepting the remote → local object sets
+// were stored as before rules had an order and a type.
+type mappingSetJSON []mappingRule
+
+func (s *mappingSetJSON) UnmarshalJSON(data []byte) error {
+	var rules []mappingRule
+	if err := json.Unmarshal(data, &rules); err == nil {
+		*s = rules
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*s = prefixRules(m)
+	return nil
+}
+
+func prefixRules(m map[string]string) []mappingRule {
+	rules := make([]mappingRule, 0, len(m))
+	for rem, loc := range m {
+		rules = append(rules, mappingRule{Remote: rem, Local: loc})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Remote < rules[j].Remote })
+	return rules
+}
+
 func mappingsFile() string {
 	return filepath.Join(configDir(), "mappings.json")
 }
 
+// initMappings loads mappings.json. Files written before sets existed are a
+// plain remote → local object and load as the default set.
 func initMappings() {
 	data, err := os.ReadFile(mappingsFile())
 	if err != nil {
 		return
 	}
-	var m map[string]string
-	if json.Unmarshal(data, &m) == nil {
-		mappingMu.Lock()
-		prefixMap = m
-		mappingMu.Unlock()
+	var d struct {
+		Active string                    `json:"active"`
+		Sets   map[string]mappingSetJSON `json:"sets"`
+	}
+	sets := map[string][]mappingRule{}
+	if json.Unmarshal(data, &d) == nil && d.Sets != nil {
+		for name, rules := range d.Sets {
+			sets[name] = rules
+		}
+	} else {
+		var m map[string]string
+		if json.Unmarshal(data, &m) != nil {
+			return
+		}
+		d.Active = defaultMappingSet
+		sets[defaultMappingSet] = prefixRules(m)
+	}
+	if _, ok := sets[d.Active]; !ok {
+		d.Active = defaultMappingSet
+	}
+	if _, ok := sets[defaultMappingSet]; !ok {
+		sets[defaultMappingSet] = nil
+	}
+	mappingMu.Lock()
+	mappingSets, activeMappingSet = sets, d.Active
+	activeTable = compileMappings(sets[d.Active])
+	mappingMu.Unlock()
+}
+
+// globRegexp compiles a glob rule: the pattern must match a leading part of
+// the path up to a segment boundary, and the rest is captured last.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			sb.WriteString("(.*)")
+			i++
+		case c == '*':
+			sb.WriteString("([^/]*)")
+		case c == '?':
+			sb.WriteString("([^/])")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(/.*)?$")
+	return regexp.Compile(sb.String())
+}
+
+// compile validates r and prepares its pattern.
+func (r mappingRule) compile() (mappingRule, error) {
+	var err error
+	switch r.Type {
+	case "":
+	case "glob":
+		r.re, err = globRegexp(r.Remote)
+	case "regex":
+		r.re, err = regexp.Compile(r.Remote)
+	default:
+		err = fmt.Errorf("unknown mapping type %q", r.Type)
+	}
+	return r, err
+}
+
+// compileMappings builds the table for rules, skipping invalid patterns.
+func compileMappings(rules []mappingRule) mappingTable {
+	t := mappingTable{exact: map[string]mappingRule{}}
+	for _, r := range rules {
+		r, err := r.compile()
+		if err != nil {
+			continue
+		}
+		if r.Type != "" {
+			t.patterns = append(t.patterns, r)
+			continue
+		}
+		t.exact[r.Remote] = r
+		t.prefixes = append(t.prefixes, r)
+	}
+	sort.SliceStable(t.prefixes, func(i, j int) bool {
+		a, b := t.prefixes[i].Remote, t.prefixes[j].Remote
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return t
+}
+
+// resolve maps remote with t and returns the rule that applied.
+func (t mappingTable) resolve(remote string) (string, mappingRule, bool) {
+	if r, ok := t.exact[remote]; ok {
+		return r.Local, r, true
+	}
+	for _, r := range t.patterns {
+		m := r.re.FindStringSubmatchIndex(remote)
+		if m == nil {
+			continue
+		}
+		if r.Type == "glob" {
+			// The last group is the rest of the path after the pattern.
+			rest := ""
+			if n := len(m); m[n-2] >= 0 {
+				rest = remote[m[n-2]:m[n-1]]
+			}
+			local := r.re.ExpandString(nil, r.Local, remote, m)
+			return strings.TrimSuffix(string(local), "/") + rest, r, true
+		}
+		local := r.re.ExpandString(nil, r.Local, remote, m)
+		return remote[:m[0]] + string(local) + remote[m[1]:], r, true
+	}
+	for _, r := range t.prefixes {
+		rem := strings.TrimSuffix(r.Remote, "/")
+		if strings.HasPrefix(remote, rem+"/") {
+			return strings.TrimSuffix(r.Local, "/") + remote[len(rem):], r, true
+		}
 	}
+	return "", mappingRule{}, false
 }
 
-// resolveLocalPath maps a remote path to a local one using cached prefix pairs.
+// resolveLocalPath maps a remote path to a local one using the active set.
 // Returns the local path and true, or ("", false) if no mapping is known.
 func resolveLocalPath(remote string) (string, bool) {
+	local, _, ok := resolveMapping(remote)
+	return local, ok
+}
+
+// resolveMapping is resolveLocalPath that also returns the rule that
+// applied, the zero rule when the path is used as-is.
+func resolveMapping(remote string) (string, mappingRule, bool) {
 	mappingMu.RLock()
-	defer mappingMu.RUnlock()
-	for rem, loc := range prefixMap {
-		if strings.HasPrefix(remote, rem+"/") || remote == rem {
-			return loc + remote[len(rem):], true
-		}
+	local, rule, ok := activeTable.resolve(remote)
+	mappingMu.RUnlock()
+	if ok {
+		return local, rule, true
 	}
 	if _, err := os.Stat(remote); err == nil {
-		return remote, true // path is accessible as-is
+		return remote, mappingRule{}, true // path is accessible as-is
 	}
-	return "", false
+	return "", mappingRule{}, false
 }
 
 // addMapping learns a remote→local prefix pair from one concrete file example
@@ -72,10 +266,7 @@ func addMapping(remote, local string) {
 		locPrefix = local
 	}
 
-	mappingMu.Lock()
-	prefixMap[remPrefix] = locPrefix
-	mappingMu.Unlock()
-	saveMappings()
+	putMapping("", mappingRule{Remote: remPrefix, Local: locPrefix}) //nolint:errcheck
 }
 
 func saveMappings() {
@@ -84,7 +275,183 @@ func saveMappings() {
 		return
 	}
 	mappingMu.RLock()
-	data, _ := json.MarshalIndent(prefixMap, "", "  ")
+	data, _ := json.MarshalIndent(mappingsData{Active: activeMappingSet, Sets: mappingSets}, "", "  ")
 	mappingMu.RUnlock()
 	os.WriteFile(f, data, 0o644) //nolint:errcheck
 }
+
+// listMappings returns the active set's rules in their order.
+func listMappings() []mappingRule {
+	mappingMu.RLock()
+	defer mappingMu.RUnlock()
+	return append([]mappingRule{}, mappingSets[activeMappingSet]...)
+}
+
+// cleanMappingPath trims whitespace and trailing slashes, which would stop a
+// prefix from matching.
+func cleanMappingPath(p string) string {
+	p = strings.TrimSpace(p)
+	if len(p) > 1 {
+		p = strings.TrimRight(p, "/")
+	}
+	return p
+}
+
+// setActiveRules replaces the active set's rules; callers hold mappingMu.
+func setActiveRules(rules []mappingRule) {
+	mappingSets[activeMappingSet] = rules
+	activeTable = compileMappings(rules)
+}
+
+// putMapping adds r to the active set, or replaces the rule whose remote is
+// old (or r.Remote when old is empty) in place, keeping its position.
+func putMapping(old string, r mappingRule) error {
+	r.Local = strings.TrimSpace(r.Local)
+	if r.Type == "" {
+		r.Remote, r.Local = cleanMappingPath(r.Remote), cleanMappingPath(r.Local)
+	} else {
+		r.Remote = strings.TrimSpace(r.Remote)
+	}
+	if r.Remote == "" || r.Local == "" {
+		return errors.New("remote and local must not be empty")
+	}
+	if _, err := r.compile(); err != nil {
+		return err
+	}
+	r.re = nil
+	if old == "" {
+		old = r.Remote
+	}
+	mappingMu.Lock()
+	var rules []mappingRule
+	replaced := false
+	for _, existing := range mappingSets[activeMappingSet] {
+		switch {
+		case existing.Remote == old && !replaced:
+			rules, replaced = append(rules, r), true
+		case existing.Remote == r.Remote || existing.Remote == old:
+			// dropped: r takes its place
+		default:
+			rules = append(rules, existing)
+		}
+	}
+	if !replaced {
+		rules = append(rules, r)
+	}
+	setActiveRules(rules)
+	mappingMu.Unlock()
+	saveMappings()
+	return nil
+}
+
+// moveMapping moves the rule with remote by delta positions within the active
+// set, which decides the order patterns are tried in.
+func moveMapping(remote string, delta int) bool {
+	mappingMu.Lock()
+	rules := append([]mappingRule{}, mappingSets[activeMappingSet]...)
+	for i, r := range rules {
+		if r.Remote != remote {
+			continue
+		}
+		j := max(0, min(len(rules)-1, i+delta))
+		rules = append(rules[:i], rules[i+1:]...)
+		rules = append(rules[:j], append([]mappingRule{r}, rules[j:]...)...)
+		setActiveRules(rules)
+		mappingMu.Unlock()
+		saveMappings()
+		return true
+	}
+	mappingMu.Unlock()
+	return false
+}
+
+// deleteMapping removes the rule with remote from the active set and reports
+// whether it was there.
+func deleteMapping(remote string) bool {
+	remote = strings.TrimSpace(remote)
+	mappingMu.Lock()
+	var rules []mappingRule
+	for _, r := range mappingSets[activeMappingSet] {
+		if r.Remote != remote {
+			rules = append(rules, r)
+		}
+	}
+	ok := len(rules) < len(mappingSets[activeMappingSet])
+	if ok {
+		setActiveRules(rules)
+	}
+	mappingMu.Unlock()
+	if ok {
+		saveMappings()
+	}
+	return ok
+}
+
+// mappingSetNames returns the sorted set names and the active one.
+func mappingSetNames() ([]string, string) {
+	mappingMu.RLock()
+	defer mappingMu.RUnlock()
+	names := make([]string, 0, len(mappingSets))
+	for name := range mappingSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, activeMappingSet
+}
+
+// useMappingSet makes the set called name active, creating it if needed.
+func useMappingSet(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("empty set name")
+	}
+	mappingMu.Lock()
+	if _, ok := mappingSets[name]; !ok {
+		mappingSets[name] = nil
+	}
+	activeMappingSet = name
+	activeTable = compileMappings(mappingSets[name])
+	mappingMu.Unlock()
+	saveMappings()
+	return nil
+}
+
+// deleteMappingSet removes the set called name; the default set cannot be
+// removed, and removing the active one switches to the default.
+func deleteMappingSet(name string) error {
+	if name == defaultMappingSet {
+		return errors.New("the default set cannot be deleted")
+	}
+	mappingMu.Lock()
+	if _, ok := mappingSets[name]; !ok {
+		mappingMu.Unlock()
+		return errors.New("unknown set " + name)
+	}
+	delete(mappingSets, name)
+	if activeMappingSet == name {
+		activeMappingSet = defaultMappingSet
+		activeTable = compileMappings(mappingSets[defaultMappingSet])
+	}
+	mappingMu.Unlock()
+	saveMappings()
+	return nil
+}
+
+// mappingTest is what "Mapping testen" shows for a sample remote path.
+type mappingTest struct {
+	Local  string `json:"local,omitempty"`
+	Rule   string `json:"rule,omitempty"` // remote of the rule that matched, "" if none
+	Type   string `json:"type,omitempty"` // its type
+	Exists bool   `json:"exists"`         // the local file is there
+}
+
+// testMapping resolves remote like resolveLocalPath and reports which
+// rule applied and whether the result exists.
+func testMapping(remote string) mappingTest {
+	local, rule, ok := resolveMapping(remote)
+	if !ok {
+		return mappingTest{}
+	}
+	_, err := os.Stat(local)
+	return mappingTest{Local: local, Rule: rule.Remote, Type: rule.Type, Exists: err == nil}
+}
@@ -11,14 +11,15 @@ import (
 
 func resetPrefixMap(t *testing.T) {
 	t.Helper()
-	mappingMu.Lock()
-	prefixMap = map[string]string{}
-	mappingMu.Unlock()
-	t.Cleanup(func() {
+	reset := func() {
 		mappingMu.Lock()
-		prefixMap = map[string]string{}
+		mappingSets = map[string][]mappingRule{defaultMappingSet: nil}
+		activeMappingSet = defaultMappingSet
+		activeTable = compileMappings(nil)
 		mappingMu.Unlock()
-	})
+	}
+	reset()
+	t.Cleanup(reset)
 }
 
 func TestResolveLocalPathExistingFile(t *testing.T) {
@@ -55,11 +56,7 @@ func TestAddMappingDeducesCommonPrefix(t *testing.T) {
 	// 4 trailing components are identical → prefix is just the first component of each
 	addMapping("/remote/project/src/controllers/Foo.php", "/local/project/src/controllers/Foo.php")
 
-	mappingMu.RLock()
-	locPrefix, ok := prefixMap["/remote"]
-	mappingMu.RUnlock()
-	assert.True(t, ok)
-	assert.Equal(t, "/local", locPrefix)
+	assert.Equal(t, []mappingRule{{Remote: "/remote", Local: "/local"}}, listMappings())
 }
 
 func TestAddMappingExactWhenNoCommonSuffix(t *testing.T) {
@@ -69,8 +66,182 @@ func TestAddMappingExactWhenNoCommonSuffix(t *testing.T) {
 
 	addMapping("/remote/foo.php", "/local/bar.php")
 
-	mappingMu.RLock()
-	_, ok := prefixMap["/remote/foo.php"]
-	mappingMu.RUnlock()
-	assert.True(t, ok)
+	assert.Equal(t, []mappingRule{{Remote: "/remote/foo.php", Local: "/local/bar.php"}}, listMappings())
+}
+
+func TestMappingCRUD(t *testing.T) {
+	resetPrefixMap(t)
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	require.NoError(t, putMapping("", mappingRule{Remote: "/var/www/html/", Local: " /Users/me/shop "}))
+	require.NoError(t, putMapping("", mappingRule{Remote: "/srv/api", Local: "/Users/me/api"}))
+	assert.Error(t, putMapping("", mappingRule{Local: "/x"}))
+	assert.Error(t, putMapping("", mappingRule{Remote: "[", Local: "/x", Type: "regex"}))
+	assert.Error(t, putMapping("", mappingRule{Remote: "/x", Local: "/x", Type: "wildcard"}))
+	assert.Equal(t, []mappingRule{
+		{Remote: "/var/www/html", Local: "/Users/me/shop"},
+		{Remote: "/srv/api", Local: "/Users/me/api"},
+	}, listMappings())
+
+	// Editing the remote prefix replaces the old entry in place.
+	require.NoError(t, putMapping("/var/www/html", mappingRule{Remote: "/var/www", Local: "/Users/me/shop"}))
+	assert.Equal(t, []mappingRule{
+		{Remote: "/var/www", Local: "/Users/me/shop"},
+		{Remote: "/srv/api", Local: "/Users/me/api"},
+	}, listMappings())
+
+	assert.True(t, deleteMapping("/srv/api"))
+	assert.False(t, deleteMapping("/srv/api"))
+	assert.Equal(t, []mappingRule{{Remote: "/var/www", Local: "/Users/me/shop"}}, listMappings())
+
+	// A wrong pick is fixed and survives a reload.
+	initMappings()
+	assert.Equal(t, []mappingRule{{Remote: "/var/www", Local: "/Users/me/shop"}}, listMappings())
+}
+
+func TestMappingSets(t *testing.T) {
+	resetPrefixMap(t)
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	require.NoError(t, putMapping("", mappingRule{Remote: "/var/www", Local: "/Users/me/shop"}))
+	require.NoError(t, useMappingSet("blog"))
+	assert.Empty(t, listMappings())
+	require.NoError(t, putMapping("", mappingRule{Remote: "/var/www", Local: "/Users/me/blog"}))
+
+	got, _ := resolveLocalPath("/var/www/index.php")
+	assert.Equal(t, "/Users/me/blog/index.php", got)
+
+	initMappings()
+	names, active := mappingSetNames()
+	assert.Equal(t, []string{"blog", "default"}, names)
+	assert.Equal(t, "blog", active)
+
+	require.NoError(t, useMappingSet(defaultMappingSet))
+	got, _ = resolveLocalPath("/var/www/index.php")
+	assert.Equal(t, "/Users/me/shop/index.php", got)
+
+	assert.Error(t, deleteMappingSet(defaultMappingSet))
+	require.NoError(t, useMappingSet("blog"))
+	require.NoError(t, deleteMappingSet("blog"))
+	names, active = mappingSetNames()
+	assert.Equal(t, []string{"default"}, names)
+	assert.Equal(t, defaultMappingSet, active)
+}
+
+func TestInitMappingsLegacyFormat(t *testing.T) {
+	resetPrefixMap(t)
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	require.NoError(t, os.WriteFile(mappingsFile(), []byte(`{"/remote":"/local"}`), 0o644))
+	initMappings()
+	assert.Equal(t, []mappingRule{{Remote: "/remote", Local: "/local"}}, listMappings())
+	_, active := mappingSetNames()
+	assert.Equal(t, defaultMappingSet, active)
+}
+
+func TestTestMapping(t *testing.T) {
+	resetPrefixMap(t)
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Foo.php"), []byte{}, 0o644))
+	require.NoError(t, putMapping("", mappingRule{Remote: "/var/www", Local: dir}))
+
+	assert.Equal(t, mappingTest{Local: filepath.Join(dir, "Foo.php"), Rule: "/var/www", Exists: true}, testMapping("/var/www/Foo.php"))
+	assert.Equal(t, mappingTest{Local: filepath.Join(dir, "Bar.php"), Rule: "/var/www"}, testMapping("/var/www/Bar.php"))
+	assert.Equal(t, mappingTest{}, testMapping("/elsewhere/Foo.php"))
+}
+
+func TestInitMappingsUnorderedSets(t *testing.T) {
+	resetPrefixMap(t)
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	data := `{"active":"default","sets":{"default":{"/var/www":"/a","/srv":"/b"}}}`
+	require.NoError(t, os.WriteFile(mappingsFile(), []byte(data), 0o644))
+	initMappings()
+	assert.Equal(t, []mappingRule{{Remote: "/srv", Local: "/b"}, {Remote: "/var/www", Local: "/a"}}, listMappings())
+}
+
+func TestResolveMappingPrecedence(t *testing.T) {
+	rules := []mappingRule{
+		{Remote: "/", Local: "/root"},
+		{Remote: "/var/www", Local: "/Users/me/www"},
+		{Remote: "/var/www/html/vendor", Local: "/Users/me/vendor"},
+		{Remote: "/var/www/html", Local: "/Users/me/shop"},
+		{Remote: "/var/www/html/index.php", Local: "/Users/me/front.php"},
+		{Remote: "/var/www/htmlx", Local: "/Users/me/htmlx"},
+		{Remote: "/home/*/app", Local: "/Users/me/${1}", Type: "glob"},
+		{Remote: "/opt/**/lib", Local: "/Users/me/lib", Type: "glob"},
+		{Remote: `^/srv/releases/\d+/`, Local: "/Users/me/app/", Type: "regex"},
+		{Remote: `^/srv/(\w+)/current`, Local: "/Users/me/$1", Type: "regex"},
+	}
+	tests := []struct {
+		name, remote, want, rule string
+	}{
+		{"longest prefix wins", "/var/www/html/vendor/a/B.php", "/Users/me/vendor/a/B.php", "/var/www/html/vendor"},
+		{"shorter prefix for siblings", "/var/www/html/src/A.php", "/Users/me/shop/src/A.php", "/var/www/html"},
+		{"outer prefix", "/var/www/other/A.php", "/Users/me/www/other/A.php", "/var/www"},
+		{"prefix matches whole segments only", "/var/www/htmlx/A.php", "/Users/me/htmlx/A.php", "/var/www/htmlx"},
+		{"exact file beats prefix", "/var/www/html/index.php", "/Users/me/front.php", "/var/www/html/index.php"},
+		{"exact file is not a prefix", "/var/www/html/index.php.bak", "/Users/me/shop/index.php.bak", "/var/www/html"},
+		{"root prefix", "/etc/hosts", "/root/etc/hosts", "/"},
+		{"glob segment", "/home/alice/app/src/A.php", "/Users/me/alice/src/A.php", "/home/*/app"},
+		{"glob beats prefix", "/home/bob/app/A.php", "/Users/me/bob/A.php", "/home/*/app"},
+		{"glob double star", "/opt/x/y/lib/a.go", "/Users/me/lib/a.go", "/opt/**/lib"},
+		{"glob needs segment boundary", "/home/alice/apps/A.php", "/root/home/alice/apps/A.php", "/"},
+		{"regex", "/srv/releases/42/src/A.php", "/Users/me/app/src/A.php", `^/srv/releases/\d+/`},
+		{"regex capture", "/srv/blog/current/A.php", "/Users/me/blog/A.php", `^/srv/(\w+)/current`},
+	}
+	table := compileMappings(rules)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Resolution must not depend on the order the rules are listed in.
+			for i := 0; i < 20; i++ {
+				got, rule, ok := table.resolve(tt.remote)
+				require.True(t, ok)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.rule, rule.Remote)
+			}
+		})
+	}
+
+	reversed := make([]mappingRule, len(rules))
+	for i, r := range rules {
+		reversed[len(rules)-1-i] = r
+	}
+	table = compileMappings(reversed)
+	got, _, _ := table.resolve("/var/www/html/vendor/a/B.php")
+	assert.Equal(t, "/Users/me/vendor/a/B.php", got)
+}
+
+func TestResolveMappingPatternOrder(t *testing.T) {
+	first := mappingRule{Remote: "/srv/*", Local: "/first", Type: "glob"}
+	second := mappingRule{Remote: "^/srv/api", Local: "/second", Type: "regex"}
+
+	got, _, _ := compileMappings([]mappingRule{first, second}).resolve("/srv/api/A.php")
+	assert.Equal(t, "/first/A.php", got)
+	got, _, _ = compileMappings([]mappingRule{second, first}).resolve("/srv/api/A.php")
+	assert.Equal(t, "/second/A.php", got)
+}
+
+func TestMoveMapping(t *testing.T) {
+	resetPrefixMap(t)
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	require.NoError(t, putMapping("", mappingRule{Remote: "/srv/*", Local: "/first", Type: "glob"}))
+	require.NoError(t, putMapping("", mappingRule{Remote: "^/srv/api", Local: "/second", Type: "regex"}))
+	got, _ := resolveLocalPath("/srv/api/A.php")
+	assert.Equal(t, "/first/A.php", got)
+
+	assert.True(t, moveMapping("^/srv/api", -1))
+	assert.False(t, moveMapping("/missing", -1))
+	got, _ = resolveLocalPath("/srv/api/A.php")
+	assert.Equal(t, "/second/A.php", got)
+	assert.Equal(t, "^/srv/api", listMappings()[0].Remote)
 }
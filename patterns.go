@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxPatterns bounds memory when messages are too diverse to cluster.
+	maxPatterns = 2000
+	// patternSim is the share of matching tokens needed to join a cluster.
+	patternSim = 0.5
+	wildcard   = "<*>"
+)
+
+// logPattern is a message template with placeholders for the variable parts,
+// e.g. "Payment <*> failed for user <*>".
+type logPattern struct {
+	ID        int            `json:"id"`
+	Template  string         `json:"template"`
+	Count     int            `json:"count"`
+	Levels    map[string]int `json:"levels"`
+	FirstSeen time.Time      `json:"firstSeen"`
+	LastSeen  time.Time      `json:"lastSeen"`
+	Sample    string         `json:"sample"`
+	tokens    []string
+}
+
+// patternMiner clusters Log Entry messages with the Drain algorithm: messages
+// are bucketed by token count and first token, then joined to the most similar
+// template in that bucket, whose differing tokens become placeholders.
+type patternMiner struct {
+	mu       sync.Mutex
+	groups   map[string][]*logPattern
+	patterns []*logPattern
+}
+
+func newPatternMiner() *patternMiner {
+	return &patternMiner{groups: map[string][]*logPattern{}}
+}
+
+// patternTokens splits a message into tokens, masking any token that contains
+// a digit since those are almost always IDs, counters or timestamps.
+func patternTokens(msg string) []string {
+	tokens := strings.Fields(msg)
+	for i, t := range tokens {
+		if strings.IndexFunc(t, unicode.IsDigit) >= 0 {
+			tokens[i] = wildcard
+		}
+	}
+	return tokens
+}
+
+// tokenSimilarity is the share of tokens the template accepts; a placeholder
+// accepts anything, so a message scores 1 against its own template.
+func tokenSimilarity(template, tokens []string) float64 {
+	same := 0
+	for i, t := range template {
+		if t == tokens[i] || t == wildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// add files e under its pattern and returns that pattern's ID, or 0 when the
+// message is empty or the pattern limit has been reached.
+func (m *patternMiner) add(e logEntry) int {
+	tokens := patternTokens(e.Message)
+	if len(tokens) == 0 {
+		return 0
+	}
+	ts := e.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	key := patternKey(tokens)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	best := m.closest(key, tokens)
+	if best == nil {
+		if len(m.patterns) >= maxPatterns {
+			return 0
+		}
+		best = &logPattern{
+			ID:        len(m.patterns) + 1,
+			Levels:    map[string]int{},
+			FirstSeen: ts,
+			Sample:    e.Message,
+			tokens:    tokens,
+		}
+		m.patterns = append(m.patterns, best)
+		m.groups[key] = append(m.groups[key], best)
+	} else {
+		for i, t := range best.tokens {
+			if t != tokens[i] {
+				best.tokens[i] = wildcard
+			}
+		}
+	}
+	best.Template = strings.Join(best.tokens, " ")
+	best.Count++
+	if e.Level != "" {
+		best.Levels[e.Level]++
+	}
+	if ts.Before(best.FirstSeen) {
+		best.FirstSeen = ts
+	}
+	if ts.After(best.LastSeen) {
+		best.LastSeen = ts
+	}
+	return best.ID
+}
+
+func patternKey(tokens []string) string {
+	return fmt.Sprintf("%d %s", len(tokens), tokens[0])
+}
+
+// closest returns the pattern in bucket key that tokens would join, or nil.
+// Must be called with m.mu held.
+func (m *patternMiner) closest(key string, tokens []string) *logPattern {
+	var best *logPattern
+	var bestSim float64
+	for _, p := range m.groups[key] {
+		if s := tokenSimilarity(p.tokens, tokens); s > bestSim {
+			best, bestSim = p, s
+		}
+	}
+	if bestSim < patternSim {
+		return nil
+	}
+	return best
+}
+
+// match returns the ID of the pattern e's message falls under without
+// counting it, for entries read again such as /lines pages; 0 if none.
+func (m *patternMiner) match(e logEntry) int {
+	tokens := patternTokens(e.Message)
+	if len(tokens) == 0 {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p := m.closest(patternKey(tokens), tokens); p != nil {
+		return p.ID
+	}
+	return 0
+}
+
+// list returns copies of the patterns, most frequent first.
+func (m *patternMiner) list() []logPattern {
+	m.mu.Lock()
+	out := make([]logPattern, len(m.patterns))
+	for i, p := range m.patterns {
+		out[i] = *p
+		out[i].Levels = make(map[string]int, len(p.Levels))
+		for k, v := range p.Levels {
+			out[i].Levels[k] = v
+		}
+	}
+	m.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+func (m *patternMiner) reset() {
+	m.mu.Lock()
+	m.groups = map[string][]*logPattern{}
+	m.patterns = nil
+	m.mu.Unlock()
+}
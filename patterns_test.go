@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternTokensMasksNumbers(t *testing.T) {
+	assert.Equal(t, []string{"order", "<*>", "took", "<*>"}, patternTokens("order #4711 took 12ms"))
+}
+
+func TestPatternMinerClustersVariableParts(t *testing.T) {
+	m := newPatternMiner()
+	base := time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)
+	for i := range 50 {
+		m.add(logEntry{
+			Message: fmt.Sprintf("Payment failed for user %d with card %s", i, []string{"visa", "amex"}[i%2]),
+			Level:   "ERROR",
+			Time:    base.Add(time.Duration(i) * time.Second),
+		})
+	}
+	m.add(logEntry{Message: "Cache warmed", Level: "INFO", Time: base})
+
+	patterns := m.list()
+	require.Len(t, patterns, 2)
+	p := patterns[0]
+	assert.Equal(t, "Payment failed for user <*> with card <*>", p.Template)
+	assert.Equal(t, 50, p.Count)
+	assert.Equal(t, 50, p.Levels["ERROR"])
+	assert.Equal(t, base, p.FirstSeen)
+	assert.Equal(t, base.Add(49*time.Second), p.LastSeen)
+	assert.Equal(t, "Cache warmed", patterns[1].Template)
+}
+
+func TestPatternMinerMatchDoesNotCount(t *testing.T) {
+	m := newPatternMiner()
+	id := m.add(logEntry{Message: "GET /orders took 12ms"})
+	require.NotZero(t, id)
+	assert.Equal(t, id, m.match(logEntry{Message: "GET /orders took 40ms"}))
+	assert.Zero(t, m.match(logEntry{Message: "something else entirely"}))
+	assert.Equal(t, 1, m.list()[0].Count)
+}
+
+func TestBrokerTagsEntriesWithTheirPattern(t *testing.T) {
+	b := newBroker()
+	b.publish("a.log", `{"msg":"GET /orders took 12ms"}`)
+	b.publish("a.log", `{"msg":"Cache warmed"}`)
+	b.publish("a.log", `{"msg":"GET /orders took 40ms"}`)
+	hist := b.snapshot()
+	assert.NotZero(t, hist[0].M)
+	assert.NotEqual(t, hist[0].M, hist[1].M)
+	assert.Equal(t, hist[0].M, hist[2].M)
+}
+
+func TestPatternMinerSeparatesDissimilarMessages(t *testing.T) {
+	m := newPatternMiner()
+	a := m.add(logEntry{Message: "user login ok"})
+	b := m.add(logEntry{Message: "user export started"})
+	c := m.add(logEntry{Message: "user login ok"})
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, c)
+}
+
+func TestPatternMinerJoinsRepeatedNumericMessages(t *testing.T) {
+	m := newPatternMiner()
+	var ids []int
+	for range 5 {
+		ids = append(ids, m.add(logEntry{Message: "GET /api/users/1 200 15ms"}))
+	}
+	patterns := m.list()
+	require.Len(t, patterns, 1)
+	assert.Equal(t, "GET <*> <*> <*>", patterns[0].Template)
+	assert.Equal(t, 5, patterns[0].Count)
+	assert.Equal(t, []int{1, 1, 1, 1, 1}, ids)
+}
+
+func TestPatternMinerIgnoresEmptyMessages(t *testing.T) {
+	m := newPatternMiner()
+	assert.Zero(t, m.add(logEntry{Message: "  "}))
+	assert.Empty(t, m.list())
+}
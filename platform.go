@@ -0,0 +1,45 @@
+package main
+
+import "unsafe"
+
+// nativeShell is everything around the webview that the desktop provides:
+// menus, the app icon, file pickers, alerts and the window frame. main talks
+// to it only through this interface; menu_darwin.go implements it with Cocoa,
+// shell_linux.go with GTK and shell_stub.go does nothing.
+//
+// All methods run on the UI thread: before wv.Run, or inside wv.Dispatch.
+// win is wv.Window(), an NSWindow* or a GtkWindow*. Menu actions are sent to
+// menuFileCh like the Cocoa menu does.
+type nativeShell interface {
+	SetupAppMenu(win unsafe.Pointer)
+	SetupAppIcon(win unsafe.Pointer)
+	SetupFileMenu(recent []string)
+	RebuildRecentMenu(recent []string)
+	RebuildWorkspaceMenu(names []string, active string)
+
+	PickLocalFile() string
+	PickMultipleFiles() []string
+	PickSaveFile(name string) string
+
+	ShowReopenAlert(count int) bool
+	ShowClearLogFilesAlert(files []string) bool
+
+	// InstallAppDelegate saves the window frame when the app quits.
+	InstallAppDelegate(win unsafe.Pointer)
+	GetWindowFrame(win unsafe.Pointer) (x, y, w, h float64)
+	SetWindowFrame(win unsafe.Pointer, x, y, w, h float64)
+}
+
+// sendMenuAction hands a menu action to the menuFileCh loop in main. Commands
+// are dropped while the loop is busy, as a double click would be; file paths
+// and workspace switches wait.
+func sendMenuAction(action string, wait bool) {
+	if wait {
+		menuFileCh <- action
+		return
+	}
+	select {
+	case menuFileCh <- action:
+	default:
+	}
+}
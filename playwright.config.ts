@@ -1,12 +1,17 @@
 import { defineConfig } from '@playwright/test';
 
+// The server requires its token on every request; tests use a fixed one.
+const token = 'e2e-token';
+
 export default defineConfig({
   testDir: './e2e',
   use: {
     baseURL: 'http://localhost:4321',
+    extraHTTPHeaders: { 'X-Jsonlv-Token': token },
   },
   webServer: {
     command: 'go build -o ./jsonlv-e2e-test . && ./jsonlv-e2e-test -headless -port 4321',
+    env: { JSONLV_TOKEN: token },
     url: 'http://localhost:4321',
     reuseExistingServer: !process.env.CI,
     timeout: 60_000,
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -16,6 +18,34 @@ type appPrefs struct {
 	Theme        string             `json:"theme,omitempty"`
 	Window       windowFrame        `json:"window,omitempty"`
 	ColumnWidths map[string]float64 `json:"columnWidths,omitempty"`
+	Dedup        bool               `json:"dedup,omitempty"`
+	// CorrelationKeys are the properties linking the entries of one request.
+	CorrelationKeys []string `json:"correlationKeys,omitempty"`
+	// Views remembers Custom Columns and property filters per viewKey.
+	Views map[string]viewPrefs `json:"views,omitempty"`
+	// Editor names the editorProfile file-path links open with; Editors adds
+	// or overrides profiles.
+	Editor  string          `json:"editor,omitempty"`
+	Editors []editorProfile `json:"editors,omitempty"`
+	// Redaction masks secrets before entries are shown; nil means
+	// defaultRedaction.
+	Redaction *redactionPrefs `json:"redaction,omitempty"`
+	// Highlights style the rows matching their queries.
+	Highlights []highlightRule `json:"highlights,omitempty"`
+}
+
+// redaction returns the redaction rules in effect.
+func (p appPrefs) redaction() redactionPrefs {
+	if p.Redaction == nil {
+		return defaultRedaction
+	}
+	return *p.Redaction
+}
+
+// viewPrefs are the Custom Columns and property filters of one set of sources.
+type viewPrefs struct {
+	CustomColumns   []string            `json:"customColumns,omitempty"`
+	PropertyFilters map[string][]string `json:"propertyFilters,omitempty"` // prop → active values
 }
 
 var (
@@ -27,19 +57,26 @@ func prefsFilePath() string {
 	return filepath.Join(configDir(), "prefs.json")
 }
 
+func defaultPrefs() appPrefs {
+	return appPrefs{Theme: "light", CorrelationKeys: defaultCorrelationKeys}
+}
+
 func loadPrefs() appPrefs {
 	data, err := os.ReadFile(prefsFilePath())
 	if err != nil {
-		return appPrefs{Theme: "light"}
+		return defaultPrefs()
 	}
 	var p appPrefs
 	if json.Unmarshal(data, &p) == nil {
 		if p.Theme == "" {
 			p.Theme = "light"
 		}
+		if len(p.CorrelationKeys) == 0 {
+			p.CorrelationKeys = defaultCorrelationKeys
+		}
 		return p
 	}
-	return appPrefs{Theme: "light"}
+	return defaultPrefs()
 }
 
 func savePrefs() {
@@ -65,3 +102,78 @@ func setWindowPref(x, y, w, h float64) {
 	prefsMu.Unlock()
 	savePrefs()
 }
+
+func setDedupPref(on bool) {
+	prefsMu.Lock()
+	curPrefs.Dedup = on
+	prefsMu.Unlock()
+	savePrefs()
+}
+
+func setEditorPref(name string) {
+	prefsMu.Lock()
+	curPrefs.Editor = name
+	prefsMu.Unlock()
+	savePrefs()
+}
+
+func setRedactionPref(r redactionPrefs) {
+	prefsMu.Lock()
+	curPrefs.Redaction = &r
+	prefsMu.Unlock()
+	savePrefs()
+}
+
+func setHighlightsPref(rules []highlightRule) {
+	prefsMu.Lock()
+	curPrefs.Highlights = rules
+	prefsMu.Unlock()
+	savePrefs()
+}
+
+func setCorrelationKeysPref(keys []string) {
+	prefsMu.Lock()
+	curPrefs.CorrelationKeys = keys
+	prefsMu.Unlock()
+	savePrefs()
+}
+
+// viewKey identifies a set of open files by their sorted basenames, so the
+// same files bring back the same view wherever they live; "-" stands for
+// stdin.
+func viewKey(files []string) string {
+	if len(files) == 0 {
+		return "-"
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = filepath.Base(f)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func viewPref(key string) (viewPrefs, bool) {
+	prefsMu.Lock()
+	defer prefsMu.Unlock()
+	v, ok := curPrefs.Views[key]
+	return v, ok
+}
+
+// setViewPref stores v under key; an empty v forgets the key.
+func setViewPref(key string, v viewPrefs) {
+	prefsMu.Lock()
+	// Copy the map: savePrefs marshals it outside the lock.
+	views := make(map[string]viewPrefs, len(curPrefs.Views)+1)
+	for k, old := range curPrefs.Views {
+		views[k] = old
+	}
+	if len(v.CustomColumns) == 0 && len(v.PropertyFilters) == 0 {
+		delete(views, key)
+	} else {
+		views[key] = v
+	}
+	curPrefs.Views = views
+	prefsMu.Unlock()
+	savePrefs()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewKey(t *testing.T) {
+	assert.Equal(t, "-", viewKey(nil))
+	assert.Equal(t, "laravel.log", viewKey([]string{"/var/www/storage/logs/laravel.log"}))
+	assert.Equal(t, "api.log, worker.log",
+		viewKey([]string{"/srv/worker.log", "/srv/api.log"}), "order of opening does not matter")
+}
+
+func TestViewPrefsPersist(t *testing.T) {
+	configDirOverride = t.TempDir()
+	old := curPrefs
+	t.Cleanup(func() {
+		configDirOverride = ""
+		curPrefs = old
+	})
+	curPrefs = defaultPrefs()
+
+	v := viewPrefs{
+		CustomColumns:   []string{"channel", "context.user_id"},
+		PropertyFilters: map[string][]string{"channel": {"payments"}},
+	}
+	setViewPref("laravel.log", v)
+
+	curPrefs = loadPrefs()
+	got, ok := viewPref("laravel.log")
+	assert.True(t, ok)
+	assert.Equal(t, v, got)
+	_, ok = viewPref("other.log")
+	assert.False(t, ok)
+
+	setViewPref("laravel.log", viewPrefs{})
+	_, ok = viewPref("laravel.log")
+	assert.False(t, ok, "an empty view is forgotten")
+}
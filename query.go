@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A query is a filter expression over Log Entries, shared by /agg, /export and
+// highlight rules. Examples:
+//
+//	level == ERROR and service != worker
+//	status_code >= 500 or (duration_ms > 1000 and not message ~ health)
+//	"connection refused"
+//
+// A comparison is a property name, an operator (== != > >= < <= ~) and a
+// value; values compare numerically when both sides are numbers, and ~ is a
+// case-insensitive substring test. A missing property only satisfies !=. A
+// quoted string or lone word matches the raw line case-insensitively.
+// Adjacent terms are implicitly and-ed.
+type query struct {
+	root queryNode
+}
+
+type queryNode interface {
+	match(e logEntry) bool
+}
+
+type (
+	andNode  struct{ l, r queryNode }
+	orNode   struct{ l, r queryNode }
+	notNode  struct{ n queryNode }
+	textNode struct{ lower string }
+	cmpNode  struct{ prop, op, val string }
+)
+
+func (n andNode) match(e logEntry) bool  { return n.l.match(e) && n.r.match(e) }
+func (n orNode) match(e logEntry) bool   { return n.l.match(e) || n.r.match(e) }
+func (n notNode) match(e logEntry) bool  { return !n.n.match(e) }
+func (n textNode) match(e logEntry) bool { return strings.Contains(strings.ToLower(e.Raw), n.lower) }
+
+func (n cmpNode) match(e logEntry) bool {
+	v, ok := e.field(n.prop)
+	if !ok {
+		return n.op == "!="
+	}
+	s := fieldString(v)
+	if n.op == "~" {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(n.val))
+	}
+	a, aNum := fieldNumber(v)
+	b, bNum := fieldNumber(n.val)
+	var c int
+	if aNum && bNum {
+		switch {
+		case a < b:
+			c = -1
+		case a > b:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(s, n.val)
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// match reports whether e satisfies q. A nil query matches everything.
+func (q *query) match(e logEntry) bool {
+	return q == nil || q.root == nil || q.root.match(e)
+}
+
+// parseQuery compiles s; an empty s yields a nil query.
+func parseQuery(s string) (*query, error) {
+	toks, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, nil
+	}
+	p := &queryParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return &query{root: root}, nil
+}
+
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+func (t queryToken) is(words ...string) bool {
+	if t.quoted {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+var queryOps = []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "=", "~", "(", ")", "!"}
+
+func lexQuery(s string) ([]queryToken, error) {
+	var toks []queryToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				sb.WriteByte(s[j])
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			toks = append(toks, queryToken{text: sb.String(), quoted: true})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range queryOps {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op != "" {
+				toks = append(toks, queryToken{text: op})
+				i += len(op)
+				continue
+			}
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\"'()=!<>~&|", rune(s[j])) {
+				j++
+			}
+			toks = append(toks, queryToken{text: s[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+type queryParser struct {
+	toks []queryToken
+	pos  int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.toks) {
+		return queryToken{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !t.is("or", "||") {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orNode{l, r}
+	}
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.is(")", "or", "||") {
+			return l, nil
+		}
+		if t.is("and", "&&") {
+			p.pos++
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andNode{l, r}
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	switch {
+	case t.is("not", "!"):
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case t.is("("):
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || !t.is(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	case t.quoted:
+		p.pos++
+		return textNode{strings.ToLower(t.text)}, nil
+	case t.is(")", "and", "or", "&&", "||", "==", "!=", ">=", "<=", ">", "<", "=", "~"):
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	p.pos++
+	op, ok := p.peek()
+	if !ok || !op.is("==", "!=", ">=", "<=", ">", "<", "=", "~") {
+		return textNode{strings.ToLower(t.text)}, nil
+	}
+	p.pos++
+	val, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("missing value after %s %s", t.text, op.text)
+	}
+	p.pos++
+	o := op.text
+	if o == "=" {
+		o = "=="
+	}
+	return cmpNode{prop: t.text, op: o, val: val.text}, nil
+}
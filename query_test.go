@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMatch(t *testing.T) {
+	line := `{"level_name":"ERROR","message":"Payment timeout","channel":"api","status_code":503,"duration_ms":1250.5,"context":{"user_id":42}}`
+	e := parseEntry("api.log", line)
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"level == ERROR", true},
+		{"level = ERROR", true},
+		{"level != ERROR", false},
+		{"status_code >= 500", true},
+		{"status_code < 500", false},
+		{"duration_ms > 1000 and service == api", true},
+		{"context.user_id == 42", true},
+		{"context.user_id == 42.0", true},
+		{"missing == x", false},
+		{"missing != x", true},
+		{"message ~ TIMEOUT", true},
+		{`"payment timeout"`, true},
+		{"timeout", true},
+		{"timeout refund", false},
+		{"level == INFO or status_code == 503", true},
+		{"not level == ERROR", false},
+		{"!(level == INFO)", true},
+		{"level == ERROR && (service == worker || _source == api.log)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseQuery(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q.match(e))
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, s := range []string{"(level == ERROR", `"open`, "level ==", "and level == x", "level == x)"} {
+		_, err := parseQuery(s)
+		assert.Error(t, err, s)
+	}
+}
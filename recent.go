@@ -9,8 +9,13 @@ import (
 const maxRecent = 10
 
 // menuFileCh carries actions from native menu callbacks to the main goroutine.
-// Values: "open" = show file picker, "clear" = clear recent list, anything
-// else is treated as a file path to tail directly.
+// Values: "open" = show file picker, "clear" = clear recent list, "export",
+// "save-session", "open-session", "restart", "clear-log-files",
+// "manage-workspaces", "workspaces" = rebuild the workspace menu and
+// "workspace:<name>" = switch to that workspace; from the control socket
+// (see controlActions) "close:<path>" = stop tailing the file,
+// "filter:<view JSON>" = apply filters and "clear-view" = clear the view;
+// anything else is treated as a file path to tail directly.
 var menuFileCh = make(chan string, 10)
 
 func recentFilePath() string {
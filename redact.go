@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// redactedMark replaces every masked value or match.
+const redactedMark = "[REDACTED]"
+
+// redactionPrefs configure what is masked before Log Entries reach the broker.
+type redactionPrefs struct {
+	Keys     []string `json:"keys,omitempty"`     // property names at any depth, case-insensitive
+	Paths    []string `json:"paths,omitempty"`    // dot paths from the root, e.g. context.user.email
+	Patterns []string `json:"patterns,omitempty"` // regexes masked in string values and plain lines
+	// Detectors names built-in detectors; see detectors.
+	Detectors []string `json:"detectors,omitempty"`
+	// KeepOriginals keeps the unmasked lines in memory, never on disk, so the
+	// UI can reveal them on request.
+	KeepOriginals bool `json:"keepOriginals,omitempty"`
+}
+
+// defaultRedaction masks secrets but not PII until configured otherwise.
+var defaultRedaction = redactionPrefs{Detectors: []string{"bearer", "password"}}
+
+// masker masks the matches of re in a string: the whole match, or only its
+// group named "secret". ok, when set, confirms a match. numberKeys, when set,
+// limits the masker to JSON numbers under those property names; strings are
+// always masked.
+type masker struct {
+	re         *regexp.Regexp
+	ok         func(match string) bool
+	numberKeys map[string]bool
+}
+
+func (m masker) apply(s string) string {
+	g := m.re.SubexpIndex("secret")
+	return m.re.ReplaceAllStringFunc(s, func(match string) string {
+		if m.ok != nil && !m.ok(match) {
+			return match
+		}
+		if g < 0 {
+			return redactedMark
+		}
+		loc := m.re.FindStringSubmatchIndex(match)
+		if loc == nil || loc[2*g] < 0 {
+			return redactedMark
+		}
+		return match[:loc[2*g]] + redactedMark + match[loc[2*g+1]:]
+	})
+}
+
+// detector is a built-in rule: property names whose values are masked and a
+// pattern masked wherever it occurs.
+type detector struct {
+	keys []string
+	masker
+}
+
+var detectors = map[string]detector{
+	"bearer": {masker: masker{re: regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`)}},
+	// A card number needs digit groups or an issuer prefix, so that epoch
+	// timestamps and IDs that happen to pass the Luhn check stay readable.
+	"card": {masker: masker{
+		re:         regexp.MustCompile(`\b(?:\d{4}[ -]\d{4,6}[ -]\d{4,5}(?:[ -]\d{1,4})?|4\d{12}(?:\d{3}){0,2}|5[1-5]\d{14}|2[2-7]\d{14}|3[47]\d{13}|6(?:011|5\d\d)\d{12})\b`),
+		ok:         luhnValid,
+		numberKeys: map[string]bool{"card": true, "card_number": true, "cardnumber": true, "pan": true, "cc": true, "cc_number": true, "credit_card": true},
+	}},
+	"email": {masker: masker{re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)}},
+	"password": {
+		keys:   []string{"password", "passwd", "pwd", "passphrase", "secret", "client_secret"},
+		masker: masker{re: regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|passphrase|secret)["']?\s*[:=]\s*["']?(?P<secret>[^\s"'&,;]+)`)},
+	},
+}
+
+// luhnValid reports whether the digits in s pass the Luhn check of card
+// numbers, which rules out most other long numbers.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// redactor applies one set of redactionPrefs to log lines.
+type redactor struct {
+	keys    map[string]bool // lower-case property names
+	paths   map[string]bool // lower-case dot paths
+	maskers []masker
+}
+
+// newRedactor compiles p; it fails on unknown detectors and bad patterns.
+func newRedactor(p redactionPrefs) (*redactor, error) {
+	r := &redactor{keys: map[string]bool{}, paths: map[string]bool{}}
+	for _, k := range p.Keys {
+		if k = strings.TrimSpace(k); k != "" {
+			r.keys[strings.ToLower(k)] = true
+		}
+	}
+	for _, path := range p.Paths {
+		if path = strings.TrimSpace(path); path != "" {
+			r.paths[strings.ToLower(path)] = true
+		}
+	}
+	for _, name := range p.Detectors {
+		d, ok := detectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector %q", name)
+		}
+		for _, k := range d.keys {
+			r.keys[k] = true
+		}
+		r.maskers = append(r.maskers, d.masker)
+	}
+	for _, pat := range p.Patterns {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pat, err)
+		}
+		r.maskers = append(r.maskers, masker{re: re})
+	}
+	return r, nil
+}
+
+func (r *redactor) empty() bool {
+	return len(r.keys) == 0 && len(r.paths) == 0 && len(r.maskers) == 0
+}
+
+func (r *redactor) maskText(s string) string {
+	for _, m := range r.maskers {
+		s = m.apply(s)
+	}
+	return s
+}
+
+// maskNumber is maskText for a JSON number under the property named key.
+func (r *redactor) maskNumber(s, key string) string {
+	for _, m := range r.maskers {
+		if m.numberKeys == nil || m.numberKeys[key] {
+			s = m.apply(s)
+		}
+	}
+	return s
+}
+
+// redact returns line with its secrets masked and whether anything was. A
+// JSON object keeps its key order; other lines only get the patterns.
+func (r *redactor) redact(line string) (string, bool) {
+	if r == nil || r.empty() {
+		return line, false
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		var out bytes.Buffer
+		changed, err := r.copyValue(dec, &out, "", false)
+		if err == nil {
+			if _, err := dec.Token(); err == io.EOF {
+				if !changed {
+					return line, false
+				}
+				return out.String(), true
+			}
+		}
+	}
+	masked := r.maskText(line)
+	return masked, masked != line
+}
+
+// copyValue copies the next JSON value from dec to out, masking it whole when
+// mask is set and matching keys, paths and patterns inside it otherwise.
+func (r *redactor) copyValue(dec *json.Decoder, out *bytes.Buffer, path string, mask bool) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if d, ok := tok.(json.Delim); ok {
+		if mask {
+			if err := skipValue(dec); err != nil {
+				return false, err
+			}
+			writeJSONString(out, redactedMark)
+			return true, nil
+		}
+		changed := false
+		if d == '[' {
+			out.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				c, err := r.copyValue(dec, out, path, false)
+				if err != nil {
+					return false, err
+				}
+				changed = changed || c
+			}
+			out.WriteByte(']')
+		} else {
+			out.WriteByte('{')
+			for i := 0; dec.More(); i++ {
+				kt, err := dec.Token()
+				if err != nil {
+					return false, err
+				}
+				key, _ := kt.(string)
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				writeJSONString(out, key)
+				out.WriteByte(':')
+				p := strings.ToLower(key)
+				if path != "" {
+					p = path + "." + p
+				}
+				c, err := r.copyValue(dec, out, p, r.keys[strings.ToLower(key)] || r.paths[p])
+				if err != nil {
+					return false, err
+				}
+				changed = changed || c
+			}
+			out.WriteByte('}')
+		}
+		_, err := dec.Token() // the closing delimiter
+		return changed, err
+	}
+	switch v := tok.(type) {
+	case string:
+		if mask && v != "" {
+			writeJSONString(out, redactedMark)
+			return true, nil
+		}
+		m := r.maskText(v)
+		writeJSONString(out, m)
+		return m != v, nil
+	case json.Number:
+		key := path[strings.LastIndexByte(path, '.')+1:]
+		if m := r.maskNumber(v.String(), key); mask || m != v.String() {
+			writeJSONString(out, redactedMark)
+			return true, nil
+		}
+		out.WriteString(v.String())
+	case bool:
+		if mask {
+			writeJSONString(out, redactedMark)
+			return true, nil
+		}
+		fmt.Fprint(out, v)
+	case nil:
+		out.WriteString("null")
+	}
+	return false, nil
+}
+
+// skipValue consumes the rest of the object or array just opened.
+func skipValue(dec *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string without escaping <, > and &, so
+// masked lines read like the originals.
+func writeJSONString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s) //nolint:errcheck
+
+	// Drop the newline Encode ends with.
+	out.Truncate(out.Len() - 1)
+}
+
+// maxOriginals bounds the originals kept for reveal, like the broker history.
+const maxOriginals = maxHistory
+
+var (
+	redactMu      sync.Mutex
+	curRedactor   *redactor
+	keepOriginals bool
+	originals     = map[int64]keptOriginal{} // redaction id → unmasked line
+	originalIDs   []int64                    // oldest first
+	originalAt    = map[string]int64{}       // source and offset → redaction id
+	lastRedactID  int64
+)
+
+// keptOriginal is an unmasked line and, if it was read at a known offset, its
+// key in originalAt.
+type keptOriginal struct {
+	line, at string
+}
+
+// setRedaction makes p the rules for entries published from now on. Turning
+// KeepOriginals off forgets the kept originals.
+func setRedaction(p redactionPrefs) error {
+	r, err := newRedactor(p)
+	if err != nil {
+		return err
+	}
+	redactMu.Lock()
+	curRedactor = r
+	keepOriginals = p.KeepOriginals
+	if !keepOriginals {
+		clear(originals)
+		clear(originalAt)
+		originalIDs = nil
+	}
+	redactMu.Unlock()
+	return nil
+}
+
+// redactMsg masks msg.D with the current rules. A masked message gets an id
+// in msg.R, under which its original is kept when KeepOriginals is set. A line
+// read again at the same offset, as when /lines pages are fetched again, gets
+// its earlier id instead of taking another place among the kept originals.
+func redactMsg(msg logMsg) logMsg {
+	redactMu.Lock()
+	r := curRedactor
+	redactMu.Unlock()
+	masked, ok := r.redact(msg.D)
+	if !ok {
+		return msg
+	}
+	var at string
+	if msg.O != nil {
+		at = fmt.Sprintf("%s\x00%d", msg.S, *msg.O)
+	}
+	redactMu.Lock()
+	id, seen := originalAt[at]
+	if !seen || at == "" || originals[id].line != msg.D {
+		lastRedactID++
+		id = lastRedactID
+		if keepOriginals {
+			originals[id] = keptOriginal{line: msg.D, at: at}
+			originalIDs = append(originalIDs, id)
+			if at != "" {
+				originalAt[at] = id
+			}
+			if len(originalIDs) > maxOriginals {
+				old := originals[originalIDs[0]]
+				if originalAt[old.at] == originalIDs[0] {
+					delete(originalAt, old.at)
+				}
+				delete(originals, originalIDs[0])
+				originalIDs = originalIDs[1:]
+			}
+		}
+	}
+	redactMu.Unlock()
+	msg.D, msg.R = masked, id
+	return msg
+}
+
+var errNotKept = errors.New("original not kept")
+
+// revealOriginal returns the unmasked line of redaction id.
+func revealOriginal(id int64) (string, error) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	kept, ok := originals[id]
+	if !ok {
+		return "", errNotKept
+	}
+	return kept.line, nil
+}
+
+// detectorNames lists the built-in detectors for the settings dialog.
+func detectorNames() []string {
+	names := make([]string, 0, len(detectors))
+	for name := range detectors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactDetectors(t *testing.T) {
+	r, err := newRedactor(redactionPrefs{Detectors: []string{"bearer", "card", "email", "password"}})
+	require.NoError(t, err)
+	for _, tt := range []struct{ in, want string }{
+		{`{"msg":"auth","context":{"headers":{"Authorization":"Bearer eyJhbGciOi.J9x-y_z"}}}`,
+			`{"msg":"auth","context":{"headers":{"Authorization":"Bearer [REDACTED]"}}}`},
+		{`{"msg":"paid","context":{"card":"4111 1111 1111 1111","order":1234567890123}}`,
+			`{"msg":"paid","context":{"card":"[REDACTED]","order":1234567890123}}`},
+		{`{"msg":"paid","card":4111111111111111}`, `{"msg":"paid","card":"[REDACTED]"}`},
+		{`{"msg":"signup","user":{"email":"jane.doe@example.com","password":"hunter2","id":42}}`,
+			`{"msg":"signup","user":{"email":"[REDACTED]","password":"[REDACTED]","id":42}}`},
+		{`{"msg":"login with password=hunter2 failed"}`, `{"msg":"login with password=[REDACTED] failed"}`},
+		{`plain text token Bearer abc.def from a@b.io`, `plain text token Bearer [REDACTED] from [REDACTED]`},
+	} {
+		got, ok := r.redact(tt.in)
+		assert.True(t, ok, tt.in)
+		assert.Equal(t, tt.want, got)
+	}
+
+	// Nothing to mask leaves the line untouched, formatting included.
+	line := `{ "msg": "ok", "status_code": 200, "when": null, "tags": ["a", true] }`
+	got, ok := r.redact(line)
+	assert.False(t, ok)
+	assert.Equal(t, line, got)
+}
+
+func TestRedactCardSparesTimestamps(t *testing.T) {
+	r, err := newRedactor(redactionPrefs{Detectors: []string{"card"}})
+	require.NoError(t, err)
+	// 1700000000004 and 1700000000000000004 pass the Luhn check.
+	require.True(t, luhnValid("1700000000004"))
+	require.True(t, luhnValid("1700000000000000004"))
+	for _, line := range []string{
+		`{"ts":1700000000004,"msg":"ms epoch"}`,
+		`{"ts":1700000000000000004,"msg":"ns epoch"}`,
+		`{"ts":"1700000000004","msg":"took until 1700000000000000004"}`,
+		`{"order_id":4111111111111111}`,
+	} {
+		got, ok := r.redact(line)
+		assert.False(t, ok, line)
+		assert.Equal(t, line, got)
+	}
+	got, _ := r.redact(`{"pan":4111111111111111,"msg":"card 5500000000000004"}`)
+	assert.Equal(t, `{"pan":"[REDACTED]","msg":"card [REDACTED]"}`, got)
+}
+
+func TestRedactRules(t *testing.T) {
+	r, err := newRedactor(redactionPrefs{
+		Keys:     []string{"SSN"},
+		Paths:    []string{"context.user.address"},
+		Patterns: []string{`sess-[0-9a-f]+`, `ip=(?P<secret>[\d.]+)`},
+	})
+	require.NoError(t, err)
+	got, ok := r.redact(`{"msg":"<b>sess-9f3a</b> ip=10.0.0.1","ssn":"078-05-1120","address":"kept",` +
+		`"context":{"user":{"address":{"city":"Berlin"},"ssn":[1,2]},"items":[{"ssn":"x"}]}}`)
+	assert.True(t, ok)
+	assert.Equal(t, `{"msg":"<b>[REDACTED]</b> ip=[REDACTED]","ssn":"[REDACTED]","address":"kept",`+
+		`"context":{"user":{"address":"[REDACTED]","ssn":"[REDACTED]"},"items":[{"ssn":"[REDACTED]"}]}}`, got)
+
+	_, err = newRedactor(redactionPrefs{Detectors: []string{"ssn"}})
+	assert.Error(t, err)
+	_, err = newRedactor(redactionPrefs{Patterns: []string{"("}})
+	assert.Error(t, err)
+}
+
+func TestLuhnValid(t *testing.T) {
+	assert.True(t, luhnValid("4111111111111111"))
+	assert.True(t, luhnValid("5500-0000-0000-0004"))
+	assert.False(t, luhnValid("4111111111111112"))
+	assert.False(t, luhnValid("0"), "too short for a card")
+}
+
+func TestRedactMsgKeepsOriginals(t *testing.T) {
+	t.Cleanup(func() { setRedaction(redactionPrefs{}) }) //nolint:errcheck
+	require.NoError(t, setRedaction(redactionPrefs{Keys: []string{"token"}, KeepOriginals: true}))
+
+	plain := redactMsg(logMsg{S: "a.log", D: `{"msg":"hi"}`})
+	assert.Zero(t, plain.R)
+
+	orig := `{"msg":"hi","token":"abc"}`
+	msg := redactMsg(logMsg{S: "a.log", D: orig})
+	assert.Equal(t, `{"msg":"hi","token":"[REDACTED]"}`, msg.D)
+	require.NotZero(t, msg.R)
+	line, err := revealOriginal(msg.R)
+	require.NoError(t, err)
+	assert.Equal(t, orig, line)
+
+	// Pages read again reuse the id of the same line at the same offset.
+	off := int64(120)
+	first := redactMsg(logMsg{S: "a.log", D: orig, O: &off})
+	kept := len(originalIDs)
+	again := redactMsg(logMsg{S: "a.log", D: orig, O: &off})
+	assert.Equal(t, first.R, again.R)
+	assert.Len(t, originalIDs, kept)
+	other := int64(121)
+	assert.NotEqual(t, first.R, redactMsg(logMsg{S: "a.log", D: orig, O: &other}).R)
+
+	// The broker only ever holds the masked line.
+	b := newBroker()
+	b.publish("a.log", orig)
+	assert.Equal(t, `{"msg":"hi","token":"[REDACTED]"}`, b.snapshot()[0].D)
+
+	// Without KeepOriginals nothing can be revealed.
+	require.NoError(t, setRedaction(redactionPrefs{Keys: []string{"token"}}))
+	_, err = revealOriginal(msg.R)
+	assert.ErrorIs(t, err, errNotKept)
+	msg = redactMsg(logMsg{D: orig})
+	_, err = revealOriginal(msg.R)
+	assert.ErrorIs(t, err, errNotKept)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// sessionVersion is written to every session file so the format can evolve.
+const sessionVersion = 1
+
+// restoreEnv is set for the process restartApp execs, telling it to pick up
+// the session saved just before.
+const restoreEnv = "JSONLV_RESTORE_SESSION"
+
+// session is everything needed to bring a view back: the open files with the
+// byte offset their history starts at, and the viewer state the UI reports.
+type session struct {
+	Version      int                `json:"version"`
+	Sources      []sessionSource    `json:"sources"`
+	View         sessionView        `json:"view"`
+	ColumnWidths map[string]float64 `json:"columnWidths,omitempty"`
+	Bookmarks    []bookmark         `json:"bookmarks,omitempty"`
+}
+
+type sessionSource struct {
+	Path   string `json:"path"`
+	Offset *int64 `json:"offset,omitempty"` // nil: the usual tail of the file
+}
+
+// sessionView is the UI's filter and layout state.
+type sessionView struct {
+	Level           string              `json:"level,omitempty"`
+	CustomColumns   []string            `json:"customColumns,omitempty"`
+	PropertyFilters map[string][]string `json:"propertyFilters,omitempty"` // prop → active values
+	Search          string              `json:"search,omitempty"`
+	From            float64             `json:"from,omitempty"` // timeline window in epoch ms
+	To              float64             `json:"to,omitempty"`
+	PatternOnly     string              `json:"patternOnly,omitempty"`
+	HiddenPatterns  []string            `json:"hiddenPatterns,omitempty"`
+	// OlderLines holds, per source, the first line loaded with "⇡ Ältere";
+	// it becomes that source's offset.
+	OlderLines map[string]int `json:"olderLines,omitempty"`
+}
+
+var (
+	pendingMu sync.Mutex
+	pending   *session // opened or restored, waiting for the UI to apply its view
+)
+
+// openSession reopens the files of s and hands its view to the UI on its
+// next GET /session.
+func openSession(w *Watcher, s session) {
+	if s.ColumnWidths != nil {
+		prefsMu.Lock()
+		curPrefs.ColumnWidths = s.ColumnWidths
+		prefsMu.Unlock()
+		savePrefs()
+	}
+	for _, bm := range s.Bookmarks {
+		setBookmark(bm)
+	}
+	pendingMu.Lock()
+	pending = &s
+	pendingMu.Unlock()
+	go w.Restore(s.Sources)
+}
+
+// takePendingSession returns the session waiting for the UI, once.
+func takePendingSession() *session {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	s := pending
+	pending = nil
+	return s
+}
+
+// restartSessionPath is where the session survives a restart.
+func restartSessionPath() string {
+	return filepath.Join(configDir(), "session.json")
+}
+
+func saveSession(path string, s session) error {
+	s.Version = sessionVersion
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func loadSession(path string) (session, error) {
+	var s session
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// sessionSources lists the tracked files, turning the UI's older-history
+// cursors into byte offsets via the line index.
+func (w *Watcher) sessionSources(olderLines map[string]int) []sessionSource {
+	var out []sessionSource
+	for _, path := range w.Files() {
+		src := sessionSource{Path: path}
+		if n, ok := olderLines[filepath.Base(path)]; ok {
+			if off, err := lineIndexFor(path).lineOffset(n); err == nil {
+				src.Offset = &off
+			}
+		}
+		out = append(out, src)
+	}
+	return out
+}
+
+// readFrom returns the non-empty lines of path from offset to the end of the
+// file with their offsets, keeping the last max. An offset past the end (the
+// file was truncated or rotated) yields the last max lines instead.
+func readFrom(path string, offset int64, max int) ([]string, []int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset > info.Size() {
+		return lastNLinesAt(path, max)
+	}
+	var lines []string
+	var offsets []int64
+	err = scanLines(f, offset, info.Size(), func(at int64, line string) bool {
+		if line == "" {
+			return true
+		}
+		lines = append(lines, line)
+		offsets = append(offsets, at)
+		if len(lines) > 2*max {
+			lines = append(lines[:0], lines[len(lines)-max:]...)
+			offsets = append(offsets[:0], offsets[len(offsets)-max:]...)
+		}
+		return true
+	})
+	if len(lines) > max {
+		lines = lines[len(lines)-max:]
+		offsets = offsets[len(offsets)-max:]
+	}
+	return lines, offsets, err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "sub", "s.json")
+	off := int64(42)
+	in := session{
+		Sources: []sessionSource{{Path: "/var/log/a.log", Offset: &off}, {Path: "/var/log/b.log"}},
+		View: sessionView{
+			Level:           "ERROR",
+			CustomColumns:   []string{"channel", "context.user_id"},
+			PropertyFilters: map[string][]string{"service": {"api", ""}},
+			Search:          "timeout",
+		},
+		ColumnWidths: map[string]float64{"ts": 120},
+	}
+	require.NoError(t, saveSession(p, in))
+
+	out, err := loadSession(p)
+	require.NoError(t, err)
+	assert.Equal(t, sessionVersion, out.Version)
+	in.Version = sessionVersion
+	assert.Equal(t, in, out)
+}
+
+func TestReadFromOffset(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.log")
+	require.NoError(t, os.WriteFile(p, []byte("one\ntwo\n\nthree\nfour\n"), 0o644))
+
+	lines, offsets, err := readFrom(p, 4, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", "three", "four"}, lines)
+	assert.Equal(t, []int64{4, 9, 15}, offsets)
+
+	lines, _, err = readFrom(p, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"three", "four"}, lines)
+
+	lines, offsets, err = readFrom(p, 1000, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"four"}, lines, "offset past the end falls back to the tail")
+	assert.Equal(t, []int64{15}, offsets)
+}
+
+func TestWatcherSessionSourcesAndRestore(t *testing.T) {
+	configDirOverride = t.TempDir()
+	defer func() { configDirOverride = "" }()
+	p := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(p, []byte("l0\nl1\nl2\nl3\n"), 0o644))
+
+	w := NewWatcher(newBroker())
+	w.Register(p)
+	srcs := w.sessionSources(map[string]int{"app.log": 2})
+	require.Len(t, srcs, 1)
+	require.NotNil(t, srcs[0].Offset)
+	assert.Equal(t, int64(6), *srcs[0].Offset)
+
+	b := newBroker()
+	NewWatcher(b).Restore(srcs)
+	hist, _ := b.subscribe()
+	require.Len(t, hist, 2)
+	assert.Equal(t, "l2", hist[0].D)
+	assert.Equal(t, "l3", hist[1].D)
+}
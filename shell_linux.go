@@ -0,0 +1,407 @@
+package main
+
+/*
+#cgo pkg-config: gtk+-3.0
+#include <stdlib.h>
+#include <string.h>
+#include <gtk/gtk.h>
+
+// Declarations of Go-exported callbacks (defined in shellcb_linux.go).
+extern void cMenuAction(const char *action, int wait);
+extern void cSaveWindowFrame(int x, int y, int w, int h);
+
+static GtkWindow     *gWindow        = NULL;
+static GtkWidget     *gMenuBar       = NULL;
+static GtkWidget     *gRecentMenu    = NULL;
+static GtkWidget     *gWorkspaceMenu = NULL;
+static GtkAccelGroup *gAccel         = NULL;
+
+static void freeString(char *s) { g_free(s); }
+
+// ── Menu helpers ──────────────────────────────────────────────────────────────
+
+static void onAction(GtkMenuItem *item, gpointer action) { cMenuAction((const char*)action, 0); }
+static void onWaitAction(GtkMenuItem *item, gpointer action) { cMenuAction((const char*)action, 1); }
+
+static void saveFrame(GtkWindow *win) {
+    int x, y, w, h;
+    gtk_window_get_position(win, &x, &y);
+    gtk_window_get_size(win, &w, &h);
+    cSaveWindowFrame(x, y, w, h);
+}
+
+static void onQuit(GtkMenuItem *item, gpointer data) {
+    saveFrame(gWindow);
+    gtk_main_quit();
+}
+
+// addItem appends an item that sends action to menuFileCh; the action string
+// is freed with the item.
+static GtkWidget *addItem(GtkWidget *menu, GtkWidget *item, const char *action, int wait, guint key) {
+    if (action) {
+        g_signal_connect_data(item, "activate", wait ? G_CALLBACK(onWaitAction) : G_CALLBACK(onAction),
+                              g_strdup(action), (GClosureNotify)g_free, 0);
+    }
+    if (key) {
+        gtk_widget_add_accelerator(item, "activate", gAccel, key, GDK_CONTROL_MASK, GTK_ACCEL_VISIBLE);
+    }
+    gtk_menu_shell_append(GTK_MENU_SHELL(menu), item);
+    return item;
+}
+
+static GtkWidget *addLabel(GtkWidget *menu, const char *label, const char *action, guint key) {
+    return addItem(menu, gtk_menu_item_new_with_label(label), action, 0, key);
+}
+
+static void addSeparator(GtkWidget *menu) {
+    gtk_menu_shell_append(GTK_MENU_SHELL(menu), gtk_separator_menu_item_new());
+}
+
+static GtkWidget *addSubmenu(GtkWidget *menu, const char *label) {
+    GtkWidget *item = gtk_menu_item_new_with_label(label);
+    GtkWidget *sub = gtk_menu_new();
+    gtk_menu_item_set_submenu(GTK_MENU_ITEM(item), sub);
+    gtk_menu_shell_append(GTK_MENU_SHELL(menu), item);
+    return sub;
+}
+
+static void clearMenu(GtkWidget *menu) {
+    GList *children = gtk_container_get_children(GTK_CONTAINER(menu));
+    for (GList *l = children; l; l = l->next) gtk_widget_destroy(GTK_WIDGET(l->data));
+    g_list_free(children);
+}
+
+// ── Menu bar ──────────────────────────────────────────────────────────────────
+
+// setupAppMenu puts a menu bar above the webview, which webview_go added as
+// the window's only child.
+void setupAppMenu(void *winPtr) {
+    gWindow = GTK_WINDOW(winPtr);
+    gAccel = gtk_accel_group_new();
+    gtk_window_add_accel_group(gWindow, gAccel);
+
+    GtkWidget *box = gtk_box_new(GTK_ORIENTATION_VERTICAL, 0);
+    gMenuBar = gtk_menu_bar_new();
+    gtk_box_pack_start(GTK_BOX(box), gMenuBar, FALSE, FALSE, 0);
+    GtkWidget *content = gtk_bin_get_child(GTK_BIN(gWindow));
+    if (content) {
+        g_object_ref(content);
+        gtk_container_remove(GTK_CONTAINER(gWindow), content);
+        gtk_box_pack_start(GTK_BOX(box), content, TRUE, TRUE, 0);
+        g_object_unref(content);
+    }
+    gtk_container_add(GTK_CONTAINER(gWindow), box);
+
+    GtkWidget *appMenu = addSubmenu(gMenuBar, "Log Viewer");
+    addLabel(appMenu, "Neu starten", "restart", GDK_KEY_r);
+    addSeparator(appMenu);
+    GtkWidget *quit = addLabel(appMenu, "Beenden", NULL, GDK_KEY_q);
+    g_signal_connect(quit, "activate", G_CALLBACK(onQuit), NULL);
+
+    gtk_widget_show_all(box);
+}
+
+void rebuildRecentMenuC(const char *recentNL) {
+    clearMenu(gRecentMenu);
+    gchar **paths = g_strsplit(recentNL ? recentNL : "", "\n", -1);
+    int n = 0;
+    for (gchar **p = paths; *p; p++) {
+        if (!**p) continue;
+        gchar *base = g_path_get_basename(*p);
+        GtkWidget *item = gtk_menu_item_new_with_label(base);
+        g_free(base);
+        gtk_widget_set_tooltip_text(item, *p);
+        addItem(gRecentMenu, item, *p, 1, 0);
+        n++;
+    }
+    g_strfreev(paths);
+    if (n > 0) addSeparator(gRecentMenu);
+    addLabel(gRecentMenu, "Liste leeren", "clear", 0);
+    gtk_widget_show_all(gRecentMenu);
+}
+
+void rebuildWorkspaceMenuC(const char *namesNL, const char *active) {
+    clearMenu(gWorkspaceMenu);
+    gchar **names = g_strsplit(namesNL ? namesNL : "", "\n", -1);
+    int n = 0;
+    for (gchar **p = names; *p; p++) {
+        if (!**p) continue;
+        GtkWidget *item = gtk_check_menu_item_new_with_label(*p);
+        // Set before connecting: set_active emits "activate".
+        gtk_check_menu_item_set_active(GTK_CHECK_MENU_ITEM(item), active && strcmp(*p, active) == 0);
+        gchar *action = g_strconcat("workspace:", *p, NULL);
+        addItem(gWorkspaceMenu, item, action, 1, 0);
+        g_free(action);
+        n++;
+    }
+    g_strfreev(names);
+    if (n > 0) addSeparator(gWorkspaceMenu);
+    addLabel(gWorkspaceMenu, "Arbeitsbereiche verwalten…", "manage-workspaces", 0);
+    gtk_widget_show_all(gWorkspaceMenu);
+}
+
+void setupFileMenu(const char *recentNL) {
+    GtkWidget *fileItem = gtk_menu_item_new_with_label("Datei");
+    GtkWidget *fileMenu = gtk_menu_new();
+    gtk_menu_item_set_submenu(GTK_MENU_ITEM(fileItem), fileMenu);
+    gtk_menu_shell_insert(GTK_MENU_SHELL(gMenuBar), fileItem, 1);
+
+    addLabel(fileMenu, "Öffnen…", "open", GDK_KEY_o);
+    addSeparator(fileMenu);
+    gRecentMenu = addSubmenu(fileMenu, "Zuletzt geöffnet");
+    rebuildRecentMenuC(recentNL);
+
+    addSeparator(fileMenu);
+    addLabel(fileMenu, "Exportieren…", "export", GDK_KEY_e);
+    addLabel(fileMenu, "Sitzung speichern…", "save-session", GDK_KEY_s);
+    addLabel(fileMenu, "Sitzung öffnen…", "open-session", 0);
+    gWorkspaceMenu = addSubmenu(fileMenu, "Arbeitsbereich");
+
+    addSeparator(fileMenu);
+    addLabel(fileMenu, "Log-Dateien leeren…", "clear-log-files", 0);
+    gtk_widget_show_all(fileItem);
+}
+
+// ── App icon ──────────────────────────────────────────────────────────────────
+
+static void roundRect(cairo_t *cr, double x, double y, double w, double h, double r) {
+    cairo_new_sub_path(cr);
+    cairo_arc(cr, x + w - r, y + r,     r, -G_PI / 2, 0);
+    cairo_arc(cr, x + w - r, y + h - r, r, 0, G_PI / 2);
+    cairo_arc(cr, x + r,     y + h - r, r, G_PI / 2, G_PI);
+    cairo_arc(cr, x + r,     y + r,     r, G_PI, 3 * G_PI / 2);
+    cairo_close_path(cr);
+}
+
+// row draws a level dot and a message bar; y is the top of the row.
+static void row(cairo_t *cr, double y, double r, double g, double b, double barW) {
+    cairo_set_source_rgb(cr, r, g, b);
+    cairo_arc(cr, 96 + 16, y + 16, 16, 0, 2 * G_PI);
+    cairo_fill(cr);
+    cairo_set_source_rgba(cr, 1, 1, 1, 0.82);
+    roundRect(cr, 148, y + 8, barW, 22, 11);
+    cairo_fill(cr);
+}
+
+// setupAppIcon draws the same icon as the macOS build.
+void setupAppIcon(void *winPtr) {
+    cairo_surface_t *surface = cairo_image_surface_create(CAIRO_FORMAT_ARGB32, 512, 512);
+    cairo_t *cr = cairo_create(surface);
+
+    roundRect(cr, 0, 0, 512, 512, 100);
+    cairo_set_source_rgb(cr, 0.06, 0.09, 0.14);
+    cairo_fill(cr);
+    roundRect(cr, 60, 100, 392, 312, 18);
+    cairo_set_source_rgb(cr, 0.11, 0.15, 0.21);
+    cairo_fill(cr);
+
+    row(cr, 132, 0.24, 0.73, 0.31, 240); // INFO
+    row(cr, 196, 0.89, 0.70, 0.25, 180); // WARN
+    row(cr, 260, 0.97, 0.32, 0.29, 210); // ERROR
+    row(cr, 324, 0.55, 0.55, 0.55, 150); // DEBUG
+
+    cairo_destroy(cr);
+    GdkPixbuf *icon = gdk_pixbuf_get_from_surface(surface, 0, 0, 512, 512);
+    cairo_surface_destroy(surface);
+    gtk_window_set_icon(GTK_WINDOW(winPtr), icon);
+    gtk_window_set_default_icon(icon);
+    g_object_unref(icon);
+}
+
+// ── Window geometry ───────────────────────────────────────────────────────────
+
+static gboolean onDelete(GtkWidget *win, GdkEvent *event, gpointer data) {
+    saveFrame(GTK_WINDOW(win));
+    return FALSE;
+}
+
+void installAppDelegate(void *winPtr) {
+    g_signal_connect(winPtr, "delete-event", G_CALLBACK(onDelete), NULL);
+}
+
+void getWindowFrame(void *winPtr, int *x, int *y, int *w, int *h) {
+    gtk_window_get_position(GTK_WINDOW(winPtr), x, y);
+    gtk_window_get_size(GTK_WINDOW(winPtr), w, h);
+}
+
+void setWindowFrame(void *winPtr, int x, int y, int w, int h) {
+    gtk_window_move(GTK_WINDOW(winPtr), x, y);
+    gtk_window_resize(GTK_WINDOW(winPtr), w, h);
+}
+
+// ── Alerts ────────────────────────────────────────────────────────────────────
+
+int showConfirmC(const char *title, const char *text, const char *accept, const char *cancel, int warning) {
+    GtkWidget *d = gtk_message_dialog_new(gWindow, GTK_DIALOG_MODAL | GTK_DIALOG_DESTROY_WITH_PARENT,
+        warning ? GTK_MESSAGE_WARNING : GTK_MESSAGE_QUESTION, GTK_BUTTONS_NONE, "%s", title);
+    gtk_message_dialog_format_secondary_text(GTK_MESSAGE_DIALOG(d), "%s", text);
+    gtk_dialog_add_buttons(GTK_DIALOG(d), cancel, GTK_RESPONSE_CANCEL, accept, GTK_RESPONSE_ACCEPT, NULL);
+    gtk_dialog_set_default_response(GTK_DIALOG(d), GTK_RESPONSE_ACCEPT);
+    int ok = gtk_dialog_run(GTK_DIALOG(d)) == GTK_RESPONSE_ACCEPT;
+    gtk_widget_destroy(d);
+    return ok;
+}
+
+// ── File pickers ──────────────────────────────────────────────────────────────
+
+// openFilePickerC returns the chosen paths joined by newlines, or NULL.
+char *openFilePickerC(const char *title, int multiple) {
+    GtkWidget *d = gtk_file_chooser_dialog_new(title, gWindow, GTK_FILE_CHOOSER_ACTION_OPEN,
+        "_Abbrechen", GTK_RESPONSE_CANCEL, "_Öffnen", GTK_RESPONSE_ACCEPT, NULL);
+    gtk_file_chooser_set_select_multiple(GTK_FILE_CHOOSER(d), multiple);
+    char *out = NULL;
+    if (gtk_dialog_run(GTK_DIALOG(d)) == GTK_RESPONSE_ACCEPT) {
+        GSList *files = gtk_file_chooser_get_filenames(GTK_FILE_CHOOSER(d));
+        GString *s = g_string_new(NULL);
+        for (GSList *l = files; l; l = l->next) {
+            if (s->len) g_string_append_c(s, '\n');
+            g_string_append(s, (const char*)l->data);
+        }
+        g_slist_free_full(files, g_free);
+        out = g_string_free(s, FALSE);
+    }
+    gtk_widget_destroy(d);
+    return out;
+}
+
+char *saveFilePickerC(const char *name) {
+    GtkWidget *d = gtk_file_chooser_dialog_new("Exportieren", gWindow, GTK_FILE_CHOOSER_ACTION_SAVE,
+        "_Abbrechen", GTK_RESPONSE_CANCEL, "_Speichern", GTK_RESPONSE_ACCEPT, NULL);
+    gtk_file_chooser_set_do_overwrite_confirmation(GTK_FILE_CHOOSER(d), TRUE);
+    gtk_file_chooser_set_current_name(GTK_FILE_CHOOSER(d), name);
+    char *out = NULL;
+    if (gtk_dialog_run(GTK_DIALOG(d)) == GTK_RESPONSE_ACCEPT) {
+        out = gtk_file_chooser_get_filename(GTK_FILE_CHOOSER(d));
+    }
+    gtk_widget_destroy(d);
+    return out;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+// gtkShell is the Linux nativeShell. webview_go runs on GTK 3 there, so the
+// menu bar and dialogs are plain GTK widgets in the webview's window.
+type gtkShell struct{}
+
+func newNativeShell() nativeShell { return gtkShell{} }
+
+func (gtkShell) SetupAppMenu(win unsafe.Pointer) { C.setupAppMenu(win) }
+func (gtkShell) SetupAppIcon(win unsafe.Pointer) { C.setupAppIcon(win) }
+
+func (gtkShell) SetupFileMenu(recent []string) {
+	cs := C.CString(strings.Join(recent, "\n"))
+	defer C.free(unsafe.Pointer(cs))
+	C.setupFileMenu(cs)
+}
+
+func (gtkShell) RebuildRecentMenu(recent []string) {
+	cs := C.CString(strings.Join(recent, "\n"))
+	defer C.free(unsafe.Pointer(cs))
+	C.rebuildRecentMenuC(cs)
+}
+
+func (gtkShell) RebuildWorkspaceMenu(names []string, active string) {
+	cs := C.CString(strings.Join(names, "\n"))
+	defer C.free(unsafe.Pointer(cs))
+	ca := C.CString(active)
+	defer C.free(unsafe.Pointer(ca))
+	C.rebuildWorkspaceMenuC(cs, ca)
+}
+
+// pickFiles runs the open dialog and returns the chosen paths.
+func pickFiles(title string, multiple bool) []string {
+	ct := C.CString(title)
+	defer C.free(unsafe.Pointer(ct))
+	m := C.int(0)
+	if multiple {
+		m = 1
+	}
+	p := C.openFilePickerC(ct, m)
+	if p == nil {
+		return nil
+	}
+	defer C.freeString(p)
+	s := C.GoString(p)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func (gtkShell) PickLocalFile() string {
+	if paths := pickFiles("Lokale Datei auswählen", false); len(paths) > 0 {
+		return paths[0]
+	}
+	return ""
+}
+
+func (gtkShell) PickMultipleFiles() []string {
+	return pickFiles("Log-Dateien öffnen", true)
+}
+
+func (gtkShell) PickSaveFile(name string) string {
+	cs := C.CString(name)
+	defer C.free(unsafe.Pointer(cs))
+	p := C.saveFilePickerC(cs)
+	if p == nil {
+		return ""
+	}
+	defer C.freeString(p)
+	return C.GoString(p)
+}
+
+// confirm shows a modal question and reports whether accept was chosen.
+func confirm(title, text, accept, cancel string, warning bool) bool {
+	args := []*C.char{C.CString(title), C.CString(text), C.CString(accept), C.CString(cancel)}
+	defer func() {
+		for _, a := range args {
+			C.free(unsafe.Pointer(a))
+		}
+	}()
+	w := C.int(0)
+	if warning {
+		w = 1
+	}
+	return C.showConfirmC(args[0], args[1], args[2], args[3], w) == 1
+}
+
+func (gtkShell) ShowReopenAlert(count int) bool {
+	return confirm("Letzte Sitzung wiederherstellen?",
+		fmt.Sprintf("%d zuletzt geöffnete Datei(en) erneut laden?", count), "Öffnen", "Überspringen", false)
+}
+
+func (gtkShell) ShowClearLogFilesAlert(files []string) bool {
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		if f != "" {
+			names = append(names, filepath.Base(f))
+		}
+	}
+	if len(names) == 0 {
+		return false
+	}
+	return confirm("Log-Dateien leeren?",
+		"Die folgenden Dateien werden auf der Festplatte geleert:\n\n"+strings.Join(names, "\n")+
+			"\n\nDieser Vorgang kann nicht rückgängig gemacht werden.", "Leeren", "Abbrechen", true)
+}
+
+func (gtkShell) InstallAppDelegate(win unsafe.Pointer) {
+	C.installAppDelegate(win)
+}
+
+func (gtkShell) GetWindowFrame(win unsafe.Pointer) (x, y, w, h float64) {
+	var cx, cy, cw, ch C.int
+	C.getWindowFrame(win, &cx, &cy, &cw, &ch)
+	return float64(cx), float64(cy), float64(cw), float64(ch)
+}
+
+func (gtkShell) SetWindowFrame(win unsafe.Pointer, x, y, w, h float64) {
+	C.setWindowFrame(win, C.int(x), C.int(y), C.int(w), C.int(h))
+}
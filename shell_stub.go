@@ -0,0 +1,25 @@
+//go:build !darwin && !linux
+
+package main
+
+import "unsafe"
+
+// noShell is the nativeShell of platforms without a native implementation:
+// the webview runs without menus, pickers cancel and alerts decline.
+type noShell struct{}
+
+func newNativeShell() nativeShell { return noShell{} }
+
+func (noShell) SetupAppMenu(unsafe.Pointer)                                       {}
+func (noShell) SetupAppIcon(unsafe.Pointer)                                       {}
+func (noShell) SetupFileMenu([]string)                                            {}
+func (noShell) RebuildRecentMenu([]string)                                        {}
+func (noShell) RebuildWorkspaceMenu([]string, string)                             {}
+func (noShell) PickLocalFile() string                                             { return "" }
+func (noShell) PickMultipleFiles() []string                                       { return nil }
+func (noShell) PickSaveFile(string) string                                        { return "" }
+func (noShell) ShowReopenAlert(int) bool                                          { return false }
+func (noShell) ShowClearLogFilesAlert([]string) bool                              { return false }
+func (noShell) InstallAppDelegate(unsafe.Pointer)                                 {}
+func (noShell) GetWindowFrame(unsafe.Pointer) (x, y, w, h float64)                { return }
+func (noShell) SetWindowFrame(unsafe.Pointer, float64, float64, float64, float64) {}
@@ -0,0 +1,14 @@
+package main
+
+// This file only contains //export functions.
+// CGO rule: files with //export must not have C definitions in their preamble.
+
+import "C"
+
+//export cMenuAction
+func cMenuAction(action *C.char, wait C.int) { sendMenuAction(C.GoString(action), wait != 0) }
+
+//export cSaveWindowFrame
+func cSaveWindowFrame(x, y, w, h C.int) {
+	setWindowPref(float64(x), float64(y), float64(w), float64(h))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+var (
+	spanIDKeys   = []string{"span_id", "dd.span_id"}
+	parentIDKeys = []string{"parent_id", "parent_span_id", "dd.parent_id"}
+)
+
+// spanNode is one timed operation of a trace. Log Entries are taken to be
+// written when their span starts; duration_ms gives the bar's length.
+type spanNode struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Source     string      `json:"source"`
+	Service    string      `json:"service,omitempty"`
+	Level      string      `json:"level,omitempty"`
+	StartMs    float64     `json:"startMs"` // relative to the trace's first span
+	DurationMs float64     `json:"durationMs"`
+	Entry      int         `json:"entry"` // index into the trace's entries
+	Children   []*spanNode `json:"children,omitempty"`
+	parentID   string
+	ts         time.Time
+}
+
+func firstString(e logEntry, keys []string) string {
+	for _, k := range keys {
+		if v, ok := e.lookup(k); ok {
+			if s := fieldString(v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// buildSpanTree turns the timed entries of a trace that carry a span ID into
+// a forest ordered by start time. Spans whose parent is missing from the trace
+// become roots; when several entries share a span ID the one with a duration
+// wins and the earliest timestamp starts the bar, so "started" and "finished"
+// lines collapse into one.
+func buildSpanTree(entries []traceEntry) []*spanNode {
+	byID := map[string]*spanNode{}
+	var spans []*spanNode
+	for i, te := range entries {
+		e := parseEntry(te.S, te.D)
+		id := firstString(e, spanIDKeys)
+		if id == "" || te.ts.IsZero() {
+			continue
+		}
+		var dur float64
+		if v, ok := e.lookup("duration_ms"); ok {
+			dur, _ = fieldNumber(v)
+		}
+		if prev, ok := byID[id]; ok && (prev.DurationMs > 0 || dur == 0) {
+			continue
+		}
+		n := &spanNode{
+			ID:         id,
+			Name:       e.Message,
+			Source:     te.S,
+			Service:    e.Service,
+			Level:      e.Level,
+			DurationMs: dur,
+			Entry:      i,
+			parentID:   firstString(e, parentIDKeys),
+			ts:         te.ts,
+		}
+		if prev, ok := byID[id]; !ok {
+			spans = append(spans, n)
+		} else {
+			if prev.ts.Before(n.ts) {
+				n.ts = prev.ts
+			}
+			for j, s := range spans {
+				if s == prev {
+					spans[j] = n
+				}
+			}
+		}
+		byID[id] = n
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].ts.Before(spans[j].ts) })
+	first := spans[0].ts
+	var roots []*spanNode
+	for _, n := range spans {
+		n.StartMs = float64(n.ts.Sub(first)) / float64(time.Millisecond)
+		if p := byID[n.parentID]; p != nil && !isAncestor(n, p, byID) {
+			p.Children = append(p.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	return roots
+}
+
+// isAncestor reports whether n is p or one of p's ancestors, which would make
+// attaching n below p a cycle.
+func isAncestor(n, p *spanNode, byID map[string]*spanNode) bool {
+	for seen := 0; p != nil && seen <= len(byID); seen++ {
+		if p == n {
+			return true
+		}
+		p = byID[p.parentID]
+	}
+	return false
+}
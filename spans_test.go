@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func traceOf(lines ...string) []traceEntry {
+	x := newTraceIndex(defaultCorrelationKeys)
+	for _, l := range lines {
+		x.add(logMsg{S: "api.log", D: l})
+	}
+	return x.lookup("t1")
+}
+
+func TestBuildSpanTreeNestsChildren(t *testing.T) {
+	entries := traceOf(
+		`{"time":"2024-01-15T10:00:00.020Z","trace_id":"t1","span_id":"db","parent_id":"req","duration_ms":30,"msg":"SELECT"}`,
+		`{"time":"2024-01-15T10:00:00Z","trace_id":"t1","span_id":"req","duration_ms":120,"msg":"GET /orders"}`,
+		`{"time":"2024-01-15T10:00:00.060Z","trace_id":"t1","span_id":"http","parent_id":"req","duration_ms":50,"msg":"POST /charge"}`,
+		`{"time":"2024-01-15T10:00:00.070Z","trace_id":"t1","msg":"no span"}`,
+	)
+	roots := buildSpanTree(entries)
+	require.Len(t, roots, 1)
+	req := roots[0]
+	assert.Equal(t, "GET /orders", req.Name)
+	assert.Equal(t, 0.0, req.StartMs)
+	assert.Equal(t, 120.0, req.DurationMs)
+	require.Len(t, req.Children, 2)
+	assert.Equal(t, "db", req.Children[0].ID)
+	assert.Equal(t, 20.0, req.Children[0].StartMs)
+	assert.Equal(t, "http", req.Children[1].ID)
+	assert.Equal(t, 60.0, req.Children[1].StartMs)
+	assert.Contains(t, entries[req.Children[1].Entry].D, "POST /charge")
+}
+
+func TestBuildSpanTreeOrphansAndCycles(t *testing.T) {
+	entries := traceOf(
+		`{"time":"2024-01-15T10:00:00Z","trace_id":"t1","span_id":"a","parent_id":"b"}`,
+		`{"time":"2024-01-15T10:00:01Z","trace_id":"t1","span_id":"b","parent_id":"a"}`,
+		`{"time":"2024-01-15T10:00:02Z","trace_id":"t1","span_id":"c","parent_id":"gone"}`,
+		`{"time":"2024-01-15T10:00:03Z","trace_id":"t1","span_id":"d","parent_id":"d"}`,
+	)
+	roots := buildSpanTree(entries)
+	assert.Len(t, roots, 4)
+}
+
+func TestBuildSpanTreePrefersEntryWithDuration(t *testing.T) {
+	entries := traceOf(
+		`{"time":"2024-01-15T10:00:00Z","trace_id":"t1","span_id":"a","msg":"started"}`,
+		`{"time":"2024-01-15T10:00:00.500Z","trace_id":"t1","span_id":"a","duration_ms":500,"msg":"finished"}`,
+		`{"time":"2024-01-15T10:00:00.100Z","trace_id":"t1","span_id":"b","parent_id":"a","duration_ms":200}`,
+	)
+	roots := buildSpanTree(entries)
+	require.Len(t, roots, 1)
+	assert.Equal(t, "finished", roots[0].Name)
+	assert.Equal(t, 500.0, roots[0].DurationMs)
+	require.Len(t, roots[0].Children, 1)
+	assert.Equal(t, 100.0, roots[0].Children[0].StartMs)
+}
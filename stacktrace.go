@@ -0,0 +1,177 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// stackTraceKeys are the properties searched for stack traces, in the order
+// their traces are shown.
+var stackTraceKeys = []string{
+	"exception.trace", "context.exception.trace", "exception.stacktrace",
+	"stack", "stacktrace", "stack_trace", "err.stack", "error.stack",
+	"error.stack_trace", "exception", "trace",
+}
+
+// stackFrame is one call of a stack trace. Vendor frames are library or
+// runtime code rather than the application's own.
+type stackFrame struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Function string `json:"function,omitempty"`
+	Vendor   bool   `json:"vendor,omitempty"`
+}
+
+// stackTrace is the stack trace found under one property.
+type stackTrace struct {
+	Key      string       `json:"key"`
+	Language string       `json:"language"` // go, python, java, node or php
+	Frames   []stackFrame `json:"frames"`
+}
+
+var (
+	goFrameRe     = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	goGoroutineRe = regexp.MustCompile(` in goroutine \d+$`)
+	pythonFrameRe = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)(?:, in (.+))?$`)
+	nodeFrameRe   = regexp.MustCompile(`^\s*at (?:(.+?) \()?(?:file://)?((?:/|node:|[A-Za-z]:\\)[^()]*?):(\d+):(\d+)\)?$`)
+	javaFrameRe   = regexp.MustCompile(`^\s*at ([\w$.<>/]+)\(([^:()]+)(?::(\d+))?\)`)
+	phpFrameRe    = regexp.MustCompile(`^#\d+ (/[^()]+)\((\d+)\): (.+)$`)
+	phpFileLineRe = regexp.MustCompile(`^(/\S+\.php):(\d+)$`)
+)
+
+// vendorMarkers are path parts of dependencies and language runtimes.
+var vendorMarkers = []string{
+	"/vendor/", "/node_modules/", "/site-packages/", "/dist-packages/",
+	"/pkg/mod/", "/usr/local/go/src/", "/usr/lib/go/src/", "/lib/python",
+	"node:",
+}
+
+// javaVendorPackages are the JDK and language runtime packages.
+var javaVendorPackages = []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "scala."}
+
+func isVendorFrame(f stackFrame, lang string) bool {
+	for _, m := range vendorMarkers {
+		if strings.Contains(f.File, m) || strings.HasPrefix(f.File, strings.TrimPrefix(m, "/")) {
+			return true
+		}
+	}
+	switch lang {
+	case "go":
+		return strings.HasPrefix(f.Function, "runtime.")
+	case "java":
+		for _, p := range javaVendorPackages {
+			if strings.HasPrefix(f.Function, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseStackTrace returns the frames of text and the language they were
+// written by, or no frames if text is not a stack trace.
+func parseStackTrace(text string) (string, []stackFrame) {
+	var frames []stackFrame
+	counts := map[string]int{}
+	add := func(lang string, f stackFrame) {
+		counts[lang]++
+		frames = append(frames, f)
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if m := goFrameRe.FindStringSubmatch(line); m != nil {
+			f := stackFrame{File: m[1], Line: atoi(m[2])}
+			if i > 0 {
+				f.Function = goFunction(lines[i-1])
+			}
+			add("go", f)
+		} else if m := pythonFrameRe.FindStringSubmatch(line); m != nil {
+			add("python", stackFrame{File: m[1], Line: atoi(m[2]), Function: m[3]})
+		} else if m := nodeFrameRe.FindStringSubmatch(line); m != nil {
+			add("node", stackFrame{File: m[2], Line: atoi(m[3]), Column: atoi(m[4]), Function: m[1]})
+		} else if m := javaFrameRe.FindStringSubmatch(line); m != nil {
+			add("java", stackFrame{File: javaSourcePath(m[1], m[2]), Line: atoi(m[3]), Function: m[1]})
+		} else if m := phpFrameRe.FindStringSubmatch(line); m != nil {
+			add("php", stackFrame{File: m[1], Line: atoi(m[2]), Function: m[3]})
+		} else if m := phpFileLineRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			add("php", stackFrame{File: m[1], Line: atoi(m[2])})
+		}
+	}
+	lang := ""
+	for l, n := range counts {
+		if n > counts[lang] || (n == counts[lang] && l < lang) {
+			lang = l
+		}
+	}
+	for i := range frames {
+		frames[i].Vendor = isVendorFrame(frames[i], lang)
+	}
+	return lang, frames
+}
+
+// goFunction returns the function of a Go frame from the line above its file,
+// like "main.(*Server).handle(0xc000010000)" or "created by main.main".
+func goFunction(line string) string {
+	fn := strings.TrimPrefix(strings.TrimSpace(line), "created by ")
+	fn = goGoroutineRe.ReplaceAllString(fn, "")
+	if strings.HasSuffix(fn, ")") {
+		if i := strings.LastIndex(fn, "("); i > 0 {
+			fn = fn[:i]
+		}
+	}
+	return fn
+}
+
+// javaSourcePath turns com.example.Foo.bar and Foo.java into
+// com/example/Foo.java, the path the file has below its source root. A module
+// prefix like "java.base/" is dropped.
+func javaSourcePath(function, file string) string {
+	function = function[strings.LastIndex(function, "/")+1:]
+	parts := strings.Split(function, ".")
+	if len(parts) < 3 || !strings.Contains(file, ".") {
+		return file
+	}
+	return strings.Join(parts[:len(parts)-2], "/") + "/" + file
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// stackTraceText returns v as stack trace text: strings as they are, and
+// arrays such as Monolog's exception.trace one frame per line.
+func stackTraceText(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []any:
+		lines := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				lines = append(lines, s)
+			} else if m, ok := item.(map[string]any); ok && m["file"] != nil {
+				lines = append(lines, fieldString(m["file"])+":"+fieldString(m["line"]))
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// extractStackTraces returns the stack traces of e under stackTraceKeys.
+func extractStackTraces(e logEntry) []stackTrace {
+	traces := []stackTrace{}
+	for _, key := range stackTraceKeys {
+		v, ok := e.lookup(key)
+		if !ok {
+			continue
+		}
+		if lang, frames := parseStackTrace(stackTraceText(v)); len(frames) > 0 {
+			traces = append(traces, stackTrace{Key: key, Language: lang, Frames: frames})
+		}
+	}
+	return traces
+}
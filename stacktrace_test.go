@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStackTrace(t *testing.T) {
+	tests := []struct {
+		name, text, lang string
+		want             []stackFrame
+	}{
+		{
+			name: "go",
+			text: "panic: boom\n\ngoroutine 1 [running]:\nmain.(*Server).handle(0xc000010000)\n\t/home/me/app/server.go:42 +0x1d\nnet/http.HandlerFunc.ServeHTTP(...)\n\t/root/go/pkg/mod/golang.org/x/net/http.go:10\nruntime.goexit()\n\t/usr/local/go/src/runtime/asm_amd64.s:1650 +0x1\ncreated by main.main in goroutine 1\n\t/home/me/app/main.go:12 +0x5",
+			lang: "go",
+			want: []stackFrame{
+				{File: "/home/me/app/server.go", Line: 42, Function: "main.(*Server).handle"},
+				{File: "/root/go/pkg/mod/golang.org/x/net/http.go", Line: 10, Function: "net/http.HandlerFunc.ServeHTTP", Vendor: true},
+				{File: "/home/me/app/main.go", Line: 12, Function: "main.main"},
+			},
+		},
+		{
+			name: "python",
+			text: "Traceback (most recent call last):\n  File \"/app/handler.py\", line 10, in handle\n    run()\n  File \"/usr/lib/python3.11/site-packages/flask/app.py\", line 99, in run\n    raise ValueError()\nValueError",
+			lang: "python",
+			want: []stackFrame{
+				{File: "/app/handler.py", Line: 10, Function: "handle"},
+				{File: "/usr/lib/python3.11/site-packages/flask/app.py", Line: 99, Function: "run", Vendor: true},
+			},
+		},
+		{
+			name: "java",
+			text: "java.lang.IllegalStateException: boom\n\tat com.example.shop.Cart.add(Cart.java:31)\n\tat java.base/java.lang.Thread.run(Thread.java:833)\n\tat com.example.Main.main(Unknown Source)",
+			lang: "java",
+			want: []stackFrame{
+				{File: "com/example/shop/Cart.java", Line: 31, Function: "com.example.shop.Cart.add"},
+				{File: "java/lang/Thread.java", Line: 833, Function: "java.base/java.lang.Thread.run", Vendor: true},
+				{File: "Unknown Source", Function: "com.example.Main.main"},
+			},
+		},
+		{
+			name: "node",
+			text: "TypeError: x is undefined\n    at Cart.add (/srv/app/src/cart.js:12:7)\n    at /srv/app/node_modules/express/lib/router.js:5:3\n    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)",
+			lang: "node",
+			want: []stackFrame{
+				{File: "/srv/app/src/cart.js", Line: 12, Column: 7, Function: "Cart.add"},
+				{File: "/srv/app/node_modules/express/lib/router.js", Line: 5, Column: 3, Vendor: true},
+				{File: "node:internal/process/task_queues", Line: 95, Column: 5, Function: "process.processTicksAndRejections", Vendor: true},
+			},
+		},
+		{
+			name: "php",
+			text: "#0 /var/www/html/src/Cart.php(42): App\\Cart->add()\n#1 /var/www/html/vendor/symfony/http-kernel/Kernel.php(7): App\\Cart->run()\n#2 {main}",
+			lang: "php",
+			want: []stackFrame{
+				{File: "/var/www/html/src/Cart.php", Line: 42, Function: "App\\Cart->add()"},
+				{File: "/var/www/html/vendor/symfony/http-kernel/Kernel.php", Line: 7, Function: "App\\Cart->run()", Vendor: true},
+			},
+		},
+		{
+			name: "not a trace",
+			text: "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, frames := parseStackTrace(tt.text)
+			assert.Equal(t, tt.lang, lang)
+			assert.Equal(t, tt.want, frames)
+		})
+	}
+}
+
+func TestExtractStackTraces(t *testing.T) {
+	e := parseEntry("app.log", `{"message":"boom","trace":"abc123","context":{"exception":{"class":"RuntimeException","trace":["/var/www/src/A.php:3",{"file":"/var/www/vendor/b/B.php","line":9}]}},"err":{"stack":"Error\n    at f (/srv/a.js:1:2)"}}`)
+	traces := extractStackTraces(e)
+	require.Len(t, traces, 2)
+	assert.Equal(t, stackTrace{Key: "context.exception.trace", Language: "php", Frames: []stackFrame{
+		{File: "/var/www/src/A.php", Line: 3},
+		{File: "/var/www/vendor/b/B.php", Line: 9, Vendor: true},
+	}}, traces[0])
+	assert.Equal(t, "err.stack", traces[1].Key)
+	assert.Equal(t, "node", traces[1].Language)
+
+	assert.Empty(t, extractStackTraces(parseEntry("", "plain text")))
+}
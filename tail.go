@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 	"os"
@@ -10,20 +11,29 @@ import (
 
 // lastNLines returns the last n non-empty lines of a file by reading backwards.
 func lastNLines(path string, n int) ([]string, error) {
+	lines, _, err := lastNLinesAt(path, n)
+	return lines, err
+}
+
+// lastNLinesAt is lastNLines that also returns the byte offset of each line.
+func lastNLinesAt(path string, n int) ([]string, []int64, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer f.Close()
 
 	info, err := f.Stat()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return lastNLinesIn(f, info.Size(), n)
+}
 
-	size := info.Size()
+// lastNLinesIn is lastNLinesAt for the first size bytes of f.
+func lastNLinesIn(f io.ReaderAt, size int64, n int) ([]string, []int64, error) {
 	if size == 0 || n == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	const chunk = 32 * 1024
@@ -38,7 +48,7 @@ func lastNLines(path string, n int) ([]string, error) {
 		pos -= read
 		tmp := make([]byte, read)
 		if _, err := f.ReadAt(tmp, pos); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		buf = append(tmp, buf...)
 		if bytes.Count(buf, []byte{'\n'}) > n || pos == 0 {
@@ -47,21 +57,56 @@ func lastNLines(path string, n int) ([]string, error) {
 	}
 
 	lines := strings.Split(strings.TrimRight(string(buf), "\r\n"), "\n")
+	offsets := make([]int64, len(lines))
+	off := pos
+	for i, line := range lines {
+		offsets[i] = off
+		off += int64(len(line)) + 1
+	}
 	if len(lines) > n {
 		lines = lines[len(lines)-n:]
+		offsets = offsets[len(offsets)-n:]
+	}
+	return lines, offsets, nil
+}
+
+// scanLines calls fn with the offset and text, without line ending, of every
+// line of f between off and size until fn returns false.
+func scanLines(f io.ReaderAt, off, size int64, fn func(off int64, line string) bool) error {
+	r := bufio.NewReaderSize(io.NewSectionReader(f, off, size-off), 64*1024)
+	for {
+		b, err := r.ReadBytes('\n')
+		if len(b) > 0 {
+			if !fn(off, strings.TrimRight(string(b), "\r\n")) {
+				return nil
+			}
+			off += int64(len(b))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
-	return lines, nil
 }
 
-// followFile watches path for new appended lines and publishes them.
-func followFile(path, source string, b *broker) {
+// followFile watches path for lines appended after offset from, or after the
+// current end when from is negative, and calls fn with each line and its
+// offset until stop is closed; a nil stop follows forever.
+func followFile(path string, from int64, stop <-chan struct{}, fn func(line string, off int64)) {
 	f, err := os.Open(path)
 	if err != nil {
 		return
 	}
 	defer f.Close()
 
-	if _, err = f.Seek(0, io.SeekEnd); err != nil {
+	whence := io.SeekStart
+	if from < 0 {
+		from, whence = 0, io.SeekEnd
+	}
+	lineStart, err := f.Seek(from, whence)
+	if err != nil {
 		return
 	}
 
@@ -69,6 +114,11 @@ func followFile(path, source string, b *broker) {
 	buf := make([]byte, 64*1024)
 
 	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
 		n, _ := f.Read(buf)
 		if n > 0 {
 			data := append(partial, buf[:n]...)
@@ -80,15 +130,21 @@ func followFile(path, source string, b *broker) {
 				line := strings.TrimRight(string(data[:i]), "\r")
 				data = data[i+1:]
 				if line != "" {
-					b.publish(source, line)
+					fn(line, lineStart)
 				}
+				lineStart += int64(i) + 1
 			}
 			partial = append(partial[:0], data...)
 		} else {
-			time.Sleep(100 * time.Millisecond)
+			select {
+			case <-stop:
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
 			if fi, err := f.Stat(); err == nil {
 				if cur, err := f.Seek(0, io.SeekCurrent); err == nil && cur > fi.Size() {
 					f.Seek(0, io.SeekStart) //nolint:errcheck
+					partial, lineStart = partial[:0], 0
 				}
 			}
 		}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -75,3 +76,29 @@ func TestLastNLines(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestLastNLinesAtOffsets(t *testing.T) {
+	p := writeTempLog(t, "alpha\nbe\n\ngamma\n")
+	lines, offsets, err := lastNLinesAt(p, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"be", "", "gamma"}, lines)
+	assert.Equal(t, []int64{6, 9, 10}, offsets)
+}
+
+func TestFollowFileFromOffset(t *testing.T) {
+	p := writeTempLog(t, "old\nkept\n")
+	stop := make(chan struct{})
+	defer close(stop)
+	got := make(chan string) // unbuffered: the follower waits for the reader
+	go followFile(p, 4, stop, func(line string, off int64) {
+		got <- fmt.Sprintf("%d %s", off, line)
+	})
+	assert.Equal(t, "4 kept", <-got)
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("new\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "9 new", <-got)
+}
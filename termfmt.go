@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Terminal rendering of Log Entries, shared by -tui and `jsonlv cat`. It
+// mirrors the viewer's row and its YAML details panel (toYamlHtml), with ANSI
+// colours in place of CSS classes.
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiReverse = "\x1b[7m"
+)
+
+// levelColors are the SGR codes of the level badges.
+var levelColors = map[string]string{
+	"DEBUG":    "90",
+	"INFO":     "36",
+	"WARN":     "33",
+	"ERROR":    "31",
+	"CRITICAL": "1;35",
+}
+
+// sourceColors cycle through the Sources like the viewer's fruit colours.
+var sourceColors = []string{"32", "35", "34", "33", "36", "31"}
+
+func sgr(code, s string, color bool) string {
+	if !color || code == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + ansiReset
+}
+
+func sourceColor(src string) string {
+	var h uint32
+	for i := 0; i < len(src); i++ {
+		h = h*31 + uint32(src[i])
+	}
+	return sourceColors[h%uint32(len(sourceColors))]
+}
+
+// formatEntry renders e as one line: time, level, source, Custom Columns and
+// message, with n > 1 shown as a ×n repeat counter.
+func formatEntry(e logEntry, n int, cols []string, color bool) string {
+	var sb strings.Builder
+	ts := strings.Repeat(" ", 12)
+	if !e.Time.IsZero() {
+		ts = e.Time.Local().Format("15:04:05.000")
+	}
+	sb.WriteString(sgr("2", ts, color))
+	sb.WriteByte(' ')
+	sb.WriteString(sgr(levelColors[e.Level], padRight(termSafe(e.Level), 8), color))
+	if e.Source != "" {
+		sb.WriteString(sgr(sourceColor(e.Source), termSafe(e.Source), color))
+		sb.WriteByte(' ')
+	}
+	for _, col := range cols {
+		v, _ := e.lookup(col)
+		sb.WriteString(sgr("34", termSafe(col+"="+fieldString(v)), color))
+		sb.WriteByte(' ')
+	}
+	msg := termSafe(strings.ReplaceAll(e.Message, "\n", " ⏎ "))
+	if e.Level == "ERROR" || e.Level == "CRITICAL" {
+		msg = sgr(levelColors[e.Level], msg, color)
+	}
+	sb.WriteString(msg)
+	if n > 1 {
+		sb.WriteString(sgr("1", " ×"+strconv.Itoa(n), color))
+	}
+	return sb.String()
+}
+
+// termSafe escapes the control characters of s, C0, DEL and C1, the way
+// strconv.Quote does, so text from a log cannot move the cursor, set the
+// window title or smuggle in other terminal sequences.
+func termSafe(s string) string {
+	if utf8.ValidString(s) && !strings.ContainsFunc(s, isControl) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&sb, `\x%02x`, s[i])
+		case isControl(r):
+			q := strconv.QuoteRune(r)
+			sb.WriteString(q[1 : len(q)-1])
+		default:
+			sb.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || 0x7f <= r && r <= 0x9f
+}
+
+func padRight(s string, n int) string {
+	if len(s) >= n {
+		return s + " "
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+var ansiSeq = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// visibleWidth counts the runes of s outside ANSI escape sequences.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiSeq.ReplaceAllString(s, ""))
+}
+
+// truncateANSI cuts s to width visible runes, keeping escape sequences and
+// resetting the style if anything was cut.
+func truncateANSI(s string, width int) string {
+	if visibleWidth(s) <= width {
+		return s
+	}
+	var sb strings.Builder
+	n := 0
+	for i := 0; i < len(s); {
+		if loc := ansiSeq.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+			sb.WriteString(s[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		if n >= width {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		sb.WriteString(s[i : i+size])
+		i += size
+		n++
+	}
+	sb.WriteString(ansiReset)
+	return sb.String()
+}
+
+// yamlField is one key of a JSON object, kept in document order.
+type yamlField struct {
+	key string
+	val any
+}
+
+// decodeOrdered decodes JSON like json.Unmarshal but keeps object keys in
+// their original order as []yamlField, the way the viewer shows them.
+func decodeOrdered(raw string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	return decodeOrderedValue(dec)
+}
+
+func decodeOrderedValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			arr := []any{}
+			for dec.More() {
+				v, err := decodeOrderedValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+			_, err := dec.Token()
+			return arr, err
+		}
+		obj := []yamlField{}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, yamlField{kt.(string), v})
+		}
+		_, err := dec.Token()
+		return obj, err
+	}
+	return tok, nil
+}
+
+var (
+	yamlKeywordRe = regexp.MustCompile(`(?i)^(true|false|yes|no|on|off|null|~)$`)
+	yamlNumberRe  = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+	yamlIndicator = regexp.MustCompile("^[{\\[|>&*!%@`'\":,#?]")
+)
+
+// yamlNeedsQuoting mirrors the JS function of the same name.
+func yamlNeedsQuoting(s string) bool {
+	switch {
+	case s == "", s == "...", s == "---":
+		return true
+	case yamlKeywordRe.MatchString(s), yamlNumberRe.MatchString(s), yamlIndicator.MatchString(s):
+		return true
+	case strings.Contains(s, ": "):
+		return true
+	}
+	return strings.TrimSpace(s) != s
+}
+
+// yamlLines renders a decodeOrdered value as YAML lines, like toYamlHtml.
+func yamlLines(v any, color bool) []string {
+	var buf bytes.Buffer
+	renderYaml(&buf, v, 0, color)
+	return strings.Split(strings.TrimPrefix(buf.String(), "\n"), "\n")
+}
+
+func renderYaml(buf *bytes.Buffer, v any, depth int, color bool) {
+	pad := strings.Repeat("  ", depth)
+	switch x := v.(type) {
+	case nil:
+		buf.WriteString(sgr("2", "null", color))
+	case bool:
+		buf.WriteString(sgr("35", strconv.FormatBool(x), color))
+	case json.Number:
+		buf.WriteString(sgr("33", x.String(), color))
+	case string:
+		switch {
+		case strings.Contains(x, "\n"):
+			lines := strings.Split(strings.TrimRight(x, "\n"), "\n")
+			buf.WriteString("|")
+			for _, l := range lines {
+				buf.WriteString("\n" + pad + termSafe(l))
+			}
+		case yamlNeedsQuoting(x):
+			buf.WriteString(sgr("32", "'"+termSafe(strings.ReplaceAll(x, "'", "''"))+"'", color))
+		default:
+			buf.WriteString(sgr("32", termSafe(x), color))
+		}
+	case []any:
+		if len(x) == 0 {
+			buf.WriteString(sgr("2", "[]", color))
+			return
+		}
+		for _, item := range x {
+			buf.WriteString("\n" + pad)
+			if isYamlContainer(item) {
+				buf.WriteString("-")
+			} else {
+				buf.WriteString("- ")
+			}
+			renderYaml(buf, item, depth+1, color)
+		}
+	case []yamlField:
+		if len(x) == 0 {
+			buf.WriteString(sgr("2", "{}", color))
+			return
+		}
+		for _, f := range x {
+			buf.WriteString("\n" + pad + sgr("34", termSafe(f.key), color))
+			if isYamlContainer(f.val) {
+				buf.WriteString(":")
+			} else {
+				buf.WriteString(": ")
+			}
+			renderYaml(buf, f.val, depth+1, color)
+		}
+	}
+}
+
+func isYamlContainer(v any) bool {
+	switch v.(type) {
+	case []any, []yamlField:
+		return true
+	}
+	return false
+}
+
+// entryDetails returns the YAML lines of a JSON Log Entry, or the raw line.
+func entryDetails(raw string, color bool) []string {
+	v, err := decodeOrdered(raw)
+	if err != nil {
+		return []string{termSafe(raw)}
+	}
+	return yamlLines(v, color)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatEntry(t *testing.T) {
+	e := parseEntry("laravel.log", `{"level_name":"ERROR","message":"boom\nagain","channel":"payments","context":{"user_id":7}}`)
+	got := formatEntry(e, 3, []string{"context.user_id"}, false)
+	assert.Equal(t, "             ERROR   laravel.log context.user_id=7 boom ⏎ again ×3", got)
+
+	colored := formatEntry(e, 1, nil, true)
+	assert.Contains(t, colored, "\x1b[31mERROR")
+	assert.Equal(t, visibleWidth(formatEntry(e, 1, nil, false)), visibleWidth(colored))
+}
+
+func TestTermSafeEscapesControls(t *testing.T) {
+	e := parseEntry("a.log", `{"level_name":"INFO","message":"hi \u001b[2J\u001b]0;pwned\u0007 \u009b\r","user":{"\u001bk":"v\u001b"}}`)
+	got := formatEntry(e, 1, []string{"user"}, true)
+	assert.Contains(t, got, `hi \x1b[2J\x1b]0;pwned\a \u009b\r`)
+	assert.NotContains(t, ansiSeq.ReplaceAllString(got, ""), "\x1b")
+	for _, l := range entryDetails(e.Raw, false) {
+		assert.NotContains(t, l, "\x1b")
+	}
+	assert.Contains(t, entryDetails(e.Raw, false), `  \x1bk: v\x1b`)
+	assert.Equal(t, `bad \xff`, termSafe("bad \xff"))
+	assert.Equal(t, "tab\\tü", termSafe("tab\tü"))
+}
+
+func TestTruncateANSI(t *testing.T) {
+	s := "\x1b[31mhällo\x1b[0m world"
+	assert.Equal(t, s, truncateANSI(s, 20))
+	got := truncateANSI(s, 7)
+	assert.Equal(t, 7, visibleWidth(got))
+	assert.Equal(t, "\x1b[31mhällo\x1b[0m w\x1b[0m", got)
+}
+
+func TestYamlLines(t *testing.T) {
+	v, err := decodeOrdered(`{"z":1,"a":{"b":"yes","c":[1,{"d":null}],"e":[]},"m":"x\ny","s":"plain"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"z: 1",
+		"a:",
+		"  b: 'yes'",
+		"  c:",
+		"    - 1",
+		"    -",
+		"      d: null",
+		"  e:[]",
+		"m: |",
+		"  x",
+		"  y",
+		"s: plain",
+	}, yamlLines(v, false))
+}
+
+func TestEntryDetailsNonJSON(t *testing.T) {
+	assert.Equal(t, []string{"plain text"}, entryDetails("plain text", false))
+}
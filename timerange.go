@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// parseTimeArg parses a -since/-until flag or a from/to query value. It accepts
+// the same layouts as log timestamps, plus a bare clock time ("03:10",
+// "03:10:30") meaning today in local time. An empty string yields the zero time.
+func parseTimeArg(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if c, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			y, m, d := time.Now().Date()
+			return time.Date(y, m, d, c.Hour(), c.Minute(), c.Second(), 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", s)
+}
+
+// lineStart returns the offset of the first line beginning at or after off.
+func lineStart(f io.ReaderAt, size, off int64) (int64, error) {
+	if off <= 0 {
+		return 0, nil
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, off-1, size-off+1))
+	pos := off - 1
+	for {
+		chunk, err := r.ReadSlice('\n')
+		pos += int64(len(chunk))
+		switch err {
+		case nil:
+			return pos, nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			return size, nil
+		default:
+			return 0, err
+		}
+	}
+}
+
+// firstTimedLine returns the bounds and timestamp of the first line starting
+// in [off, limit) that carries a parseable timestamp. start is -1 if none does.
+func firstTimedLine(f io.ReaderAt, size, off, limit int64) (start, next int64, ts time.Time, err error) {
+	pos, err := lineStart(f, size, off)
+	if err != nil {
+		return -1, 0, ts, err
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, pos, size-pos))
+	for pos < limit {
+		line, rerr := r.ReadBytes('\n')
+		if len(line) == 0 {
+			break
+		}
+		end := pos + int64(len(line))
+		if ts = parseLineTime(strings.TrimRight(string(line), "\r\n")); !ts.IsZero() {
+			return pos, end, ts, nil
+		}
+		pos = end
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return -1, 0, ts, rerr
+		}
+	}
+	return -1, 0, time.Time{}, nil
+}
+
+// seekTime binary-searches f on line boundaries and returns the offset of the
+// first line whose timestamp is not before t. Lines without a timestamp are
+// skipped while probing, so they stay attached to the entry preceding them.
+// The file is assumed to be ordered by time.
+func seekTime(f io.ReaderAt, size int64, t time.Time) (int64, error) {
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, next, ts, err := firstTimedLine(f, size, mid, hi)
+		if err != nil {
+			return 0, err
+		}
+		if start < 0 || !ts.Before(t) {
+			hi = mid
+		} else {
+			lo = next
+		}
+	}
+	// Step over untimed continuation lines of the last entry before t.
+	start, _, _, err := firstTimedLine(f, size, lo, size)
+	if err != nil || start < 0 {
+		return size, err
+	}
+	return start, nil
+}
+
+// readTimeRange returns up to max non-empty lines of path whose timestamps lie
+// within [from, to]. A zero from starts at the beginning of the file, a zero to
+// reads to the end.
+func readTimeRange(path string, from, to time.Time, max int) ([]string, error) {
+	lines, _, err := readTimeRangeAt(path, from, to, max)
+	return lines, err
+}
+
+// readTimeRangeAt is readTimeRange that also returns the byte offset of each
+// line.
+func readTimeRangeAt(path string, from, to time.Time, max int) ([]string, []int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	return readTimeRangeIn(f, info.Size(), from, to, max)
+}
+
+// readTimeRangeIn is readTimeRangeAt for the first size bytes of f.
+func readTimeRangeIn(f io.ReaderAt, size int64, from, to time.Time, max int) ([]string, []int64, error) {
+	var off int64
+	var err error
+	if !from.IsZero() {
+		if off, err = seekTime(f, size, from); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var lines []string
+	var offsets []int64
+	err = scanLines(f, off, size, func(at int64, line string) bool {
+		if line == "" {
+			return true
+		}
+		if !to.IsZero() {
+			if ts := parseLineTime(line); !ts.IsZero() && ts.After(to) {
+				return false
+			}
+		}
+		lines = append(lines, line)
+		offsets = append(offsets, at)
+		return len(lines) < max
+	})
+	return lines, offsets, err
+}
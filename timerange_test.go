@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMinuteLog writes one entry per minute starting at 03:00Z, with an
+// untimed continuation line after every entry.
+func writeMinuteLog(t *testing.T, minutes int) string {
+	t.Helper()
+	var sb strings.Builder
+	for i := range minutes {
+		fmt.Fprintf(&sb, `{"time":"2024-01-15T03:%02d:00Z","message":"m%d"}`+"\n", i, i)
+		sb.WriteString("  continuation\n")
+	}
+	return writeTempLog(t, sb.String())
+}
+
+func TestSeekTime(t *testing.T) {
+	p := writeMinuteLog(t, 60)
+	f, err := os.Open(p)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	t.Run("lands on first line at or after t", func(t *testing.T) {
+		off, err := seekTime(f, info.Size(), time.Date(2024, 1, 15, 3, 10, 0, 0, time.UTC))
+		require.NoError(t, err)
+		buf := make([]byte, 40)
+		_, err = f.ReadAt(buf, off)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(buf), `{"time":"2024-01-15T03:10:00Z"`))
+	})
+
+	t.Run("between entries lands on the next one", func(t *testing.T) {
+		off, err := seekTime(f, info.Size(), time.Date(2024, 1, 15, 3, 10, 30, 0, time.UTC))
+		require.NoError(t, err)
+		buf := make([]byte, 40)
+		_, err = f.ReadAt(buf, off)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(buf), `{"time":"2024-01-15T03:11:00Z"`))
+	})
+
+	t.Run("before first entry returns 0", func(t *testing.T) {
+		off, err := seekTime(f, info.Size(), time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), off)
+	})
+
+	t.Run("after last entry returns size", func(t *testing.T) {
+		off, err := seekTime(f, info.Size(), time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, info.Size(), off)
+	})
+}
+
+func TestReadTimeRange(t *testing.T) {
+	p := writeMinuteLog(t, 60)
+
+	t.Run("window is inclusive and keeps continuation lines", func(t *testing.T) {
+		lines, err := readTimeRange(p,
+			time.Date(2024, 1, 15, 3, 10, 0, 0, time.UTC),
+			time.Date(2024, 1, 15, 3, 12, 0, 0, time.UTC), maxHistory)
+		require.NoError(t, err)
+		require.Len(t, lines, 6)
+		assert.Contains(t, lines[0], `"m10"`)
+		assert.Equal(t, "  continuation", lines[1])
+		assert.Contains(t, lines[4], `"m12"`)
+	})
+
+	t.Run("open-ended since", func(t *testing.T) {
+		lines, err := readTimeRange(p, time.Date(2024, 1, 15, 3, 58, 0, 0, time.UTC), time.Time{}, maxHistory)
+		require.NoError(t, err)
+		assert.Len(t, lines, 4)
+	})
+
+	t.Run("capped at max", func(t *testing.T) {
+		lines, err := readTimeRange(p, time.Time{}, time.Time{}, 5)
+		require.NoError(t, err)
+		assert.Len(t, lines, 5)
+	})
+}
+
+func TestParseTimeArg(t *testing.T) {
+	got, err := parseTimeArg("2024-01-15T03:10:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1705288200), got.Unix())
+
+	got, err = parseTimeArg("03:10")
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Hour())
+	assert.Equal(t, 10, got.Minute())
+
+	got, err = parseTimeArg("")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	_, err = parseTimeArg("yesterday")
+	assert.Error(t, err)
+}
+
+func TestZonelessTimesAreLocal(t *testing.T) {
+	// TZ is read once at startup, so stand in for TZ=Europe/Berlin directly.
+	local := time.Local
+	time.Local = time.FixedZone("CET", 3600)
+	t.Cleanup(func() { time.Local = local })
+
+	p := writeTempLog(t, `{"time":"2024-01-15 03:09:00","message":"before"}`+"\n"+
+		`{"time":"2024-01-15 03:10:00","message":"at"}`+"\n"+
+		`{"time":"2024-01-15T02:11:00Z","message":"after, in UTC"}`+"\n")
+	since, err := parseTimeArg("2024-01-15 03:10:00")
+	require.NoError(t, err)
+	assert.Equal(t, since, parseLineTime(`{"time":"2024-01-15 03:10:00"}`))
+
+	lines, err := readTimeRange(p, since, time.Time{}, maxHistory)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"at"`)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCorrelationKeys are the properties that tie Log Entries of one
+// request together when the user has not configured any.
+var defaultCorrelationKeys = []string{"trace_id", "request_id", "dd.trace_id"}
+
+type traceEntry struct {
+	S        string   `json:"s"`
+	D        string   `json:"d"`
+	OffsetMs *float64 `json:"offsetMs"` // time since the trace's first entry; nil if untimed
+	ts       time.Time
+}
+
+// traceIndex maps correlation IDs to the Log Entries carrying them, across all
+// sources. Like the Broker it holds at most maxHistory entries, oldest evicted.
+type traceIndex struct {
+	mu    sync.Mutex
+	keys  []string
+	byID  map[string][]traceEntry
+	order []string // ID of every indexed entry, oldest first
+}
+
+func newTraceIndex(keys []string) *traceIndex {
+	return &traceIndex{keys: keys, byID: map[string][]traceEntry{}}
+}
+
+// correlationIDs returns the distinct IDs line carries under keys. Numbers
+// keep all their digits, so 64-bit trace IDs do not collide.
+func correlationIDs(line string, keys []string) []string {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var obj map[string]any
+	if dec.Decode(&obj) != nil || obj == nil {
+		return nil
+	}
+	e := logEntry{Fields: obj}
+	var ids []string
+	for _, k := range keys {
+		v, ok := e.lookup(k)
+		if !ok {
+			continue
+		}
+		id := fieldString(v)
+		if id == "" {
+			continue
+		}
+		dup := false
+		for _, seen := range ids {
+			dup = dup || seen == id
+		}
+		if !dup {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (x *traceIndex) add(msg logMsg) {
+	if msg.U {
+		return // a folded repeat of an entry that is already indexed
+	}
+	e := parseEntry(msg.S, msg.D)
+	if e.Fields == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, id := range correlationIDs(msg.D, x.keys) {
+		x.byID[id] = append(x.byID[id], traceEntry{S: msg.S, D: msg.D, ts: e.Time})
+		x.order = append(x.order, id)
+	}
+	for len(x.order) > maxHistory {
+		old := x.order[0]
+		x.order = x.order[1:]
+		if rest := x.byID[old][1:]; len(rest) > 0 {
+			x.byID[old] = rest
+		} else {
+			delete(x.byID, old)
+		}
+	}
+}
+
+// setKeys switches to new correlation keys and re-indexes hist.
+func (x *traceIndex) setKeys(keys []string, hist []logMsg) {
+	x.mu.Lock()
+	x.keys = keys
+	x.byID = map[string][]traceEntry{}
+	x.order = nil
+	x.mu.Unlock()
+	for _, msg := range hist {
+		x.add(msg)
+	}
+}
+
+// lookup returns the entries for id ordered by timestamp, untimed ones last,
+// with offsets relative to the earliest entry.
+func (x *traceIndex) lookup(id string) []traceEntry {
+	x.mu.Lock()
+	entries := append([]traceEntry(nil), x.byID[id]...)
+	x.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].ts, entries[j].ts
+		if ti.IsZero() != tj.IsZero() {
+			return tj.IsZero()
+		}
+		return ti.Before(tj)
+	})
+	if len(entries) > 0 && !entries[0].ts.IsZero() {
+		first := entries[0].ts
+		for i := range entries {
+			if entries[i].ts.IsZero() {
+				continue
+			}
+			off := float64(entries[i].ts.Sub(first)) / float64(time.Millisecond)
+			entries[i].OffsetMs = &off
+		}
+	}
+	return entries
+}
+
+func (x *traceIndex) reset() {
+	x.mu.Lock()
+	x.byID = map[string][]traceEntry{}
+	x.order = nil
+	x.mu.Unlock()
+}
+
+// run indexes the Broker history and then every newly published Log Entry.
+func (x *traceIndex) run(b *broker) {
+	hist, ch := b.subscribe()
+	for _, msg := range hist {
+		x.add(msg)
+	}
+	for msg := range ch {
+		x.add(msg)
+	}
+}
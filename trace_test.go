@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceIndexGroupsAcrossSources(t *testing.T) {
+	x := newTraceIndex(defaultCorrelationKeys)
+	x.add(logMsg{S: "api.log", D: `{"time":"2024-01-15T10:00:00.250Z","trace_id":"abc","msg":"db"}`})
+	x.add(logMsg{S: "api.log", D: `{"time":"2024-01-15T10:00:00Z","trace_id":"abc","msg":"start"}`})
+	x.add(logMsg{S: "worker.log", D: `{"dd":{"trace_id":"abc"},"msg":"untimed"}`})
+	x.add(logMsg{S: "worker.log", D: `{"time":"2024-01-15T10:00:01Z","request_id":"abc","msg":"job"}`})
+	x.add(logMsg{S: "api.log", D: `{"trace_id":"other","msg":"unrelated"}`})
+
+	got := x.lookup("abc")
+	require.Len(t, got, 4)
+	assert.Contains(t, got[0].D, `"start"`)
+	assert.Contains(t, got[1].D, `"db"`)
+	assert.Equal(t, "worker.log", got[2].S)
+	assert.Contains(t, got[3].D, `"untimed"`)
+
+	require.NotNil(t, got[0].OffsetMs)
+	assert.Equal(t, 0.0, *got[0].OffsetMs)
+	assert.Equal(t, 250.0, *got[1].OffsetMs)
+	assert.Equal(t, 1000.0, *got[2].OffsetMs)
+	assert.Nil(t, got[3].OffsetMs)
+}
+
+func TestTraceIndexKeepsNumericIDsAndFlatKeys(t *testing.T) {
+	x := newTraceIndex(defaultCorrelationKeys)
+	// Both IDs are the same float64.
+	x.add(logMsg{S: "a.log", D: `{"dd.trace_id":1234567890123456789,"msg":"flat"}`})
+	x.add(logMsg{S: "a.log", D: `{"dd":{"trace_id":1234567890123456790},"msg":"nested"}`})
+
+	got := x.lookup("1234567890123456789")
+	require.Len(t, got, 1)
+	assert.Contains(t, got[0].D, `"flat"`)
+	assert.Len(t, x.lookup("1234567890123456790"), 1)
+}
+
+func TestTraceIndexSetKeysReindexes(t *testing.T) {
+	hist := []logMsg{{S: "a.log", D: `{"context":{"rid":"r1"}}`}}
+	x := newTraceIndex(defaultCorrelationKeys)
+	for _, m := range hist {
+		x.add(m)
+	}
+	assert.Empty(t, x.lookup("r1"))
+
+	x.setKeys([]string{"context.rid"}, hist)
+	assert.Len(t, x.lookup("r1"), 1)
+}
+
+func TestTraceIndexSkipsFoldedUpdates(t *testing.T) {
+	x := newTraceIndex(defaultCorrelationKeys)
+	x.add(logMsg{S: "a.log", D: `{"trace_id":"t"}`})
+	x.add(logMsg{S: "a.log", D: `{"trace_id":"t"}`, N: 2, U: true})
+	assert.Len(t, x.lookup("t"), 1)
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// The -tui mode shows the Broker stream in the terminal, for SSH sessions
+// without a desktop. Keys:
+//
+//	0-5        level filter: ALL INFO WARN ERROR CRITICAL DEBUG
+//	↑↓ j k     move           PgUp PgDn  page
+//	g G        first / last (G follows new entries again)
+//	Enter ␣    expand / collapse the entry's details
+//	/ n N      search, next / previous match
+//	f          add a property filter (prop=value); F clears them
+//	c          add a Custom Column; C clears them
+//	q Ctrl+C   quit
+
+// tuiLevels are the level filters in the order of the viewer's buttons.
+var tuiLevels = []string{"ALL", "INFO", "WARN", "ERROR", "CRITICAL", "DEBUG"}
+
+type tuiEntry struct {
+	e logEntry
+	n int // folded repeats
+}
+
+// tuiView is the state of the terminal UI, kept apart from the terminal so
+// it can be tested.
+type tuiView struct {
+	entries  []tuiEntry
+	level    string
+	props    map[string]map[string]bool // prop → accepted values
+	cols     []string
+	search   string
+	visible  []int // indices into entries passing the filters
+	cursor   int   // index into visible
+	top      int   // index into visible of the first row on screen
+	follow   bool
+	expanded map[int]bool // by index into entries
+
+	width, height int
+	prompt        string // label of the active input line, "" if none
+	input         string
+	status        string
+}
+
+func newTUIView() *tuiView {
+	return &tuiView{level: "ALL", props: map[string]map[string]bool{}, follow: true, expanded: map[int]bool{}}
+}
+
+// propValue is the value property filters compare, like entryMatchesFilters.
+func propValue(e logEntry, prop string) string {
+	if prop == "_source" {
+		return e.Source
+	}
+	v, _ := e.lookup(prop)
+	return fieldString(v)
+}
+
+func (v *tuiView) matches(e logEntry) bool {
+	if v.level != "ALL" && e.Level != v.level {
+		return false
+	}
+	for prop, vals := range v.props {
+		if len(vals) > 0 && !vals[propValue(e, prop)] {
+			return false
+		}
+	}
+	return true
+}
+
+// add appends a Broker message, folding repeats into the previous entry of
+// the same source as the viewer does.
+func (v *tuiView) add(msg logMsg) {
+	if msg.U {
+		for i := len(v.entries) - 1; i >= 0; i-- {
+			if v.entries[i].e.Source == msg.S {
+				v.entries[i].n = msg.N
+				return
+			}
+		}
+	}
+	e := parseEntry(msg.S, msg.D)
+	v.entries = append(v.entries, tuiEntry{e, msg.N})
+	if v.matches(e) {
+		v.visible = append(v.visible, len(v.entries)-1)
+	}
+	if len(v.entries) > maxHistory {
+		// Trim a tenth at a time rather than one entry per line.
+		v.trim(len(v.entries) - maxHistory + maxHistory/10)
+	}
+}
+
+// trim forgets the oldest drop entries, shifting visible, cursor and top so
+// they stay on the same entries.
+func (v *tuiView) trim(drop int) {
+	v.entries = v.entries[drop:]
+	expanded := map[int]bool{}
+	for i := range v.expanded {
+		if i >= drop {
+			expanded[i-drop] = true
+		}
+	}
+	v.expanded = expanded
+	gone := 0 // visible entries dropped
+	for gone < len(v.visible) && v.visible[gone] < drop {
+		gone++
+	}
+	n := copy(v.visible, v.visible[gone:])
+	v.visible = v.visible[:n]
+	for i := range v.visible {
+		v.visible[i] -= drop
+	}
+	v.cursor = max(0, v.cursor-gone)
+	v.top = max(0, v.top-gone)
+}
+
+// refilter recomputes visible, keeping the cursor on the same entry if it is
+// still shown.
+func (v *tuiView) refilter() {
+	cur := -1
+	if v.cursor < len(v.visible) {
+		cur = v.visible[v.cursor]
+	}
+	v.visible = v.visible[:0]
+	v.cursor = 0
+	for i, te := range v.entries {
+		if v.matches(te.e) {
+			if i <= cur {
+				v.cursor = len(v.visible)
+			}
+			v.visible = append(v.visible, i)
+		}
+	}
+	v.top = min(v.top, v.cursor)
+}
+
+func (v *tuiView) setLevel(level string) {
+	v.level = level
+	v.refilter()
+}
+
+// addPropFilter parses "prop=value" and accepts value for prop.
+func (v *tuiView) addPropFilter(s string) bool {
+	prop, val, ok := strings.Cut(s, "=")
+	prop = strings.TrimSpace(prop)
+	if !ok || prop == "" {
+		return false
+	}
+	if v.props[prop] == nil {
+		v.props[prop] = map[string]bool{}
+	}
+	v.props[prop][strings.TrimSpace(val)] = true
+	v.refilter()
+	return true
+}
+
+func (v *tuiView) moveTo(i int) {
+	if len(v.visible) == 0 {
+		v.cursor = 0
+		return
+	}
+	v.cursor = max(0, min(i, len(v.visible)-1))
+	v.follow = v.cursor == len(v.visible)-1
+}
+
+// findNext moves to the next visible entry containing the search term,
+// searching backwards when dir < 0.
+func (v *tuiView) findNext(dir int) bool {
+	term := strings.ToLower(v.search)
+	if term == "" || len(v.visible) == 0 {
+		return false
+	}
+	for step := 1; step <= len(v.visible); step++ {
+		i := ((v.cursor+dir*step)%len(v.visible) + len(v.visible)) % len(v.visible)
+		if strings.Contains(strings.ToLower(v.entries[v.visible[i]].e.Raw), term) {
+			v.moveTo(i)
+			return true
+		}
+	}
+	return false
+}
+
+// rowLines renders the visible entry at i, with its details when expanded.
+func (v *tuiView) rowLines(i int) []string {
+	idx := v.visible[i]
+	te := v.entries[idx]
+	row := formatEntry(te.e, te.n, v.cols, true)
+	if i == v.cursor {
+		row = ansiReverse + ansiSeq.ReplaceAllString(row, "") + ansiReset
+	} else if v.search != "" && strings.Contains(strings.ToLower(te.e.Raw), strings.ToLower(v.search)) {
+		row = ansiBold + row
+	}
+	lines := []string{row}
+	if v.expanded[idx] {
+		for _, l := range entryDetails(te.e.Raw, true) {
+			lines = append(lines, "    "+l)
+		}
+	}
+	return lines
+}
+
+// render returns the full screen: a header, the rows from top and a status
+// or input line.
+func (v *tuiView) render() string {
+	rows := max(1, v.height-2)
+	if v.follow {
+		v.cursor = max(0, len(v.visible)-1)
+	}
+	v.scroll(rows)
+
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	header := fmt.Sprintf(" jsonlv  %d/%d  Level: %s", len(v.visible), len(v.entries), v.level)
+	if len(v.props) > 0 {
+		header += "  Filter: " + v.propSummary()
+	}
+	if v.search != "" {
+		header += "  Suche: " + v.search
+	}
+	sb.WriteString(ansiReverse + padLine(truncateANSI(header, v.width), v.width) + ansiReset + "\r\n")
+
+	n := 0
+	for i := v.top; i < len(v.visible) && n < rows; i++ {
+		for _, l := range v.rowLines(i) {
+			if n == rows {
+				break
+			}
+			sb.WriteString(truncateANSI(l, v.width) + ansiReset + "\x1b[K\r\n")
+			n++
+		}
+	}
+	for ; n < rows; n++ {
+		sb.WriteString("\x1b[K\r\n")
+	}
+
+	footer := v.status
+	if v.prompt != "" {
+		footer = v.prompt + v.input
+	} else if footer == "" {
+		footer = "0-5 Level  / Suche  f Filter  c Spalte  ⏎ Details  G Live  q Ende"
+	}
+	sb.WriteString(truncateANSI(footer, v.width) + "\x1b[K")
+	return sb.String()
+}
+
+// scroll adjusts top so the cursor's row and details fit on screen.
+func (v *tuiView) scroll(rows int) {
+	if v.cursor < v.top {
+		v.top = v.cursor
+		return
+	}
+	n := 0
+	for i := v.cursor; i >= v.top; i-- {
+		if n += len(v.rowLines(i)); n > rows {
+			v.top = min(i+1, v.cursor)
+			return
+		}
+	}
+}
+
+func (v *tuiView) propSummary() string {
+	props := make([]string, 0, len(v.props))
+	for p := range v.props {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+	var parts []string
+	for _, p := range props {
+		vals := make([]string, 0, len(v.props[p]))
+		for val := range v.props[p] {
+			vals = append(vals, val)
+		}
+		sort.Strings(vals)
+		parts = append(parts, p+"="+strings.Join(vals, "|"))
+	}
+	return strings.Join(parts, " ")
+}
+
+func padLine(s string, width int) string {
+	if n := visibleWidth(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// key handles one key press and reports whether the UI should quit.
+func (v *tuiView) key(k string) bool {
+	v.status = ""
+	if v.prompt != "" {
+		v.promptKey(k)
+		return false
+	}
+	rows := max(1, v.height-2)
+	switch k {
+	case "q", "ctrl-c":
+		return true
+	case "0", "1", "2", "3", "4", "5":
+		n, _ := strconv.Atoi(k)
+		v.setLevel(tuiLevels[n])
+	case "down", "j":
+		v.moveTo(v.cursor + 1)
+	case "up", "k":
+		v.moveTo(v.cursor - 1)
+	case "pgdown":
+		v.moveTo(v.cursor + rows)
+	case "pgup":
+		v.moveTo(v.cursor - rows)
+	case "g", "home":
+		v.moveTo(0)
+	case "G", "end":
+		v.moveTo(len(v.visible) - 1)
+		v.follow = true
+	case "enter", " ":
+		v.toggle()
+	case "/":
+		v.prompt, v.input = "Suche: ", v.search
+	case "n", "N":
+		dir := 1
+		if k == "N" {
+			dir = -1
+		}
+		if !v.findNext(dir) && v.search != "" {
+			v.status = "Keine Treffer für " + v.search
+		}
+	case "f":
+		v.prompt, v.input = "Filter (prop=wert): ", ""
+	case "F":
+		v.props = map[string]map[string]bool{}
+		v.refilter()
+	case "c":
+		v.prompt, v.input = "Spalte: ", ""
+	case "C":
+		v.cols = nil
+	}
+	return false
+}
+
+func (v *tuiView) toggle() {
+	if len(v.visible) == 0 {
+		return
+	}
+	idx := v.visible[v.cursor]
+	v.expanded[idx] = !v.expanded[idx]
+	v.follow = false
+}
+
+func (v *tuiView) promptKey(k string) {
+	switch k {
+	case "esc", "ctrl-c":
+		v.prompt, v.input = "", ""
+	case "backspace":
+		if r := []rune(v.input); len(r) > 0 {
+			v.input = string(r[:len(r)-1])
+		}
+	case "enter":
+		prompt, input := v.prompt, strings.TrimSpace(v.input)
+		v.prompt, v.input = "", ""
+		switch prompt {
+		case "Suche: ":
+			v.search = input
+			if input != "" && !v.findNext(1) {
+				v.status = "Keine Treffer für " + input
+			}
+		case "Spalte: ":
+			if input != "" {
+				v.cols = append(v.cols, input)
+			}
+		default:
+			if input != "" && !v.addPropFilter(input) {
+				v.status = "Filter als prop=wert angeben"
+			}
+		}
+	default:
+		if len([]rune(k)) == 1 {
+			v.input += k
+		}
+	}
+}
+
+// decodeKeys splits terminal input into key names: printable characters stand
+// for themselves, control keys and escape sequences get names like "up".
+func decodeKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			seqs := []struct{ seq, name string }{
+				{"\x1b[A", "up"}, {"\x1b[B", "down"}, {"\x1b[5~", "pgup"}, {"\x1b[6~", "pgdown"},
+				{"\x1b[H", "home"}, {"\x1b[F", "end"}, {"\x1b[1~", "home"}, {"\x1b[4~", "end"},
+				{"\x1bOA", "up"}, {"\x1bOB", "down"},
+			}
+			matched := false
+			for _, s := range seqs {
+				if strings.HasPrefix(string(b), s.seq) {
+					keys = append(keys, s.name)
+					b = b[len(s.seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				keys = append(keys, "esc")
+				b = b[1:]
+				// Skip the rest of an unknown CSI sequence.
+				if len(b) > 0 && b[0] == '[' {
+					i := 1
+					for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+						i++
+					}
+					keys = keys[:len(keys)-1]
+					b = b[min(i+1, len(b)):]
+				}
+			}
+			continue
+		}
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= ' ' && r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// stty runs stty on the terminal tty.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func termSize(tty *os.File) (width, height int) {
+	out, err := stty(tty, "size")
+	if err == nil {
+		if _, err := fmt.Sscan(out, &height, &width); err == nil && width > 0 && height > 0 {
+			return width, height
+		}
+	}
+	return 80, 24
+}
+
+// runTUI shows b in the terminal until the user quits. Keys are read from
+// /dev/tty so stdin can still carry the log stream.
+func runTUI(b *broker) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("no terminal: %w", err)
+	}
+	defer tty.Close()
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return fmt.Errorf("stty: %w", err)
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return fmt.Errorf("stty: %w", err)
+	}
+	out := bufio.NewWriter(tty)
+	out.WriteString("\x1b[?1049h\x1b[?25l") //nolint:errcheck
+	defer func() {
+		out.WriteString("\x1b[?25h\x1b[?1049l") //nolint:errcheck
+		out.Flush()                             //nolint:errcheck
+		stty(tty, saved)                        //nolint:errcheck
+	}()
+
+	keys := make(chan []string)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := tty.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- decodeKeys(buf[:n])
+		}
+	}()
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+
+	v := newTUIView()
+	v.width, v.height = termSize(tty)
+	hist, ch := b.subscribe()
+	defer b.unsubscribe(ch)
+	for _, msg := range hist {
+		v.add(msg)
+	}
+
+	// Redraw at most every 50ms while entries stream in.
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	dirty := true
+	for {
+		select {
+		case ks, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range ks {
+				if v.key(k) {
+					return nil
+				}
+			}
+			dirty = true
+		case msg := <-ch:
+			v.add(msg)
+			dirty = true
+		case <-winch:
+			v.width, v.height = termSize(tty)
+			out.WriteString("\x1b[2J") //nolint:errcheck
+			dirty = true
+		case <-tick.C:
+			if dirty {
+				out.WriteString(v.render()) //nolint:errcheck
+				out.Flush()                 //nolint:errcheck
+				dirty = false
+			}
+		}
+	}
+}
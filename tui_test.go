@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tuiTestView(lines ...string) *tuiView {
+	v := newTUIView()
+	v.width, v.height = 80, 10
+	for _, l := range lines {
+		v.add(logMsg{S: "app.log", D: l})
+	}
+	return v
+}
+
+func TestTUIFilters(t *testing.T) {
+	v := tuiTestView(
+		`{"level":"info","msg":"a","service":"api"}`,
+		`{"level":"error","msg":"b","service":"api"}`,
+		`{"level":"error","msg":"c","service":"worker"}`,
+	)
+	assert.Equal(t, []int{0, 1, 2}, v.visible)
+
+	v.key("3") // ERROR
+	assert.Equal(t, []int{1, 2}, v.visible)
+
+	assert.True(t, v.addPropFilter("service=worker"))
+	assert.Equal(t, []int{2}, v.visible)
+	assert.False(t, v.addPropFilter("nonsense"))
+
+	v.key("F")
+	v.key("0")
+	assert.Equal(t, []int{0, 1, 2}, v.visible)
+
+	v.add(logMsg{S: "app.log", D: `{"level":"error","msg":"c","service":"worker"}`, N: 2, U: true})
+	assert.Len(t, v.entries, 3, "repeat updates fold into the previous entry")
+	assert.Equal(t, 2, v.entries[2].n)
+}
+
+func TestTUITrimKeepsCursor(t *testing.T) {
+	v := tuiTestView(
+		`{"level":"info","msg":"a"}`,
+		`{"level":"error","msg":"b"}`,
+		`{"level":"info","msg":"c"}`,
+		`{"level":"error","msg":"d"}`,
+		`{"level":"error","msg":"e"}`,
+	)
+	v.key("3") // ERROR
+	v.moveTo(1)
+	v.expanded[3] = true
+	v.trim(2)
+	assert.Equal(t, []int{1, 2}, v.visible)
+	assert.Equal(t, 0, v.cursor)
+	assert.Equal(t, `{"level":"error","msg":"d"}`, v.entries[v.visible[v.cursor]].e.Raw)
+	assert.Equal(t, map[int]bool{1: true}, v.expanded)
+}
+
+func TestTUISearchAndPrompt(t *testing.T) {
+	v := tuiTestView(`{"msg":"alpha"}`, `{"msg":"beta"}`, `{"msg":"alphabet"}`)
+	v.moveTo(0)
+	for _, k := range []string{"/", "a", "l", "p", "x", "backspace", "h", "enter"} {
+		v.key(k)
+	}
+	assert.Equal(t, "alph", v.search)
+	assert.Equal(t, 2, v.cursor)
+	v.key("n")
+	assert.Equal(t, 0, v.cursor, "search wraps around")
+	v.key("N")
+	assert.Equal(t, 2, v.cursor)
+
+	v.key("enter")
+	assert.True(t, v.expanded[2])
+	assert.Contains(t, ansiSeq.ReplaceAllString(v.render(), ""), "msg: alphabet")
+	assert.True(t, v.key("q"))
+}
+
+func TestTUIScrollKeepsCursorVisible(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, `{"msg":"x"}`)
+	}
+	v := tuiTestView(lines...)
+	v.render()
+	assert.Equal(t, 49, v.cursor, "follows the stream")
+	assert.Equal(t, 42, v.top)
+
+	v.key("g")
+	v.render()
+	assert.Equal(t, 0, v.top)
+	assert.False(t, v.follow)
+}
+
+func TestDecodeKeys(t *testing.T) {
+	assert.Equal(t, []string{"up", "down", "pgup", "enter", "ä", "esc", "backspace", "ctrl-c"},
+		decodeKeys([]byte("\x1b[A\x1b[B\x1b[5~\rä\x1b\x7f\x03")))
+	assert.Equal(t, []string{"x"}, decodeKeys([]byte("\x1b[1;5Cx")), "unknown sequences are dropped")
+}
@@ -11,45 +11,57 @@ import (
 )
 
 type tailLine struct {
+	path   string
 	source string
 	line   string
+	off    int64
 	ts     time.Time
 }
 
+// timeLayouts lists the timestamp string formats recognised in log lines.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+}
+
 func parseLineTime(line string) time.Time {
-	var obj map[string]json.RawMessage
+	var obj map[string]any
 	if err := json.Unmarshal([]byte(line), &obj); err != nil {
 		return time.Time{}
 	}
-	for _, key := range []string{"datetime", "timestamp", "time", "@timestamp"} {
+	return entryTime(obj)
+}
+
+// timeKeys are the properties a log line's timestamp is read from, in order.
+var timeKeys = []string{"datetime", "timestamp", "time", "@timestamp"}
+
+// entryTime returns the timestamp of a decoded log line, or the zero time.
+func entryTime(obj map[string]any) time.Time {
+	for _, key := range timeKeys {
 		raw, ok := obj[key]
 		if !ok {
 			continue
 		}
-		var s string
-		if json.Unmarshal(raw, &s) == nil {
-			for _, layout := range []string{
-				time.RFC3339Nano,
-				time.RFC3339,
-				"2006-01-02 15:04:05.999999999Z07:00",
-				"2006-01-02 15:04:05.999999999",
-				"2006-01-02 15:04:05",
-				"2006-01-02T15:04:05.999999999",
-				"2006-01-02T15:04:05",
-			} {
-				if t, err := time.Parse(layout, s); err == nil {
+		switch v := raw.(type) {
+		case string:
+			for _, layout := range timeLayouts {
+				// Zone-less timestamps are local time, like -since/-until.
+				if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
 					return t
 				}
 			}
-		}
-		var f float64
-		if json.Unmarshal(raw, &f) == nil {
-			if f > 1e10 { // millisecond epoch (> year 2001 in ms)
-				ms := int64(f)
+		case float64:
+			if v > 1e10 { // millisecond epoch (> year 2001 in ms)
+				ms := int64(v)
 				return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
 			}
-			sec := int64(f)
-			return time.Unix(sec, int64((f-float64(sec))*1e9))
+			sec := int64(v)
+			return time.Unix(sec, int64((v-float64(sec))*1e9))
 		}
 	}
 	return time.Time{}
@@ -60,18 +72,60 @@ func parseLineTime(line string) time.Time {
 type Watcher struct {
 	b      *broker
 	mu     sync.Mutex
-	tailed map[string]bool
+	tailed map[string]chan struct{} // path → closed when the file is removed
 }
 
 func NewWatcher(b *broker) *Watcher {
-	return &Watcher{b: b, tailed: map[string]bool{}}
+	return &Watcher{b: b, tailed: map[string]chan struct{}{}}
+}
+
+// Register records path as open without starting a tail goroutine. The
+// returned channel is closed when path is removed; pass it to followFile.
+func (w *Watcher) Register(path string) <-chan struct{} {
+	w.mu.Lock()
+	stop, ok := w.tailed[path]
+	if !ok {
+		stop = make(chan struct{})
+		w.tailed[path] = stop
+	}
+	w.mu.Unlock()
+	indexInBackground(path)
+	return stop
 }
 
-// Register records path as open without starting a tail goroutine.
-func (w *Watcher) Register(path string) {
+// Remove stops tailing path, drops its lines from the broker and reports
+// whether it was open.
+func (w *Watcher) Remove(path string) bool {
 	w.mu.Lock()
-	w.tailed[path] = true
+	key, ok := w.lookup(path)
+	if ok {
+		close(w.tailed[key])
+		delete(w.tailed, key)
+	}
 	w.mu.Unlock()
+	if ok {
+		w.b.removeFile(key)
+		dropLineIndex(key)
+	}
+	return ok
+}
+
+// lookup returns the key under which path is tracked, also when it was opened
+// by another spelling of the same path. Must be called with w.mu held.
+func (w *Watcher) lookup(path string) (string, bool) {
+	if _, ok := w.tailed[path]; ok {
+		return path, true
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	for p := range w.tailed {
+		if pa, err := filepath.Abs(p); err == nil && pa == abs {
+			return p, true
+		}
+	}
+	return "", false
 }
 
 // Files returns the paths of all currently tracked files.
@@ -85,30 +139,49 @@ func (w *Watcher) Files() []string {
 	return files
 }
 
+// Path returns the tracked file whose basename is source.
+func (w *Watcher) Path(source string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for p := range w.tailed {
+		if filepath.Base(p) == source {
+			return p, true
+		}
+	}
+	return "", false
+}
+
 // Add begins tailing path if it is not already being watched.
 func (w *Watcher) Add(path string) {
 	w.mu.Lock()
-	already := w.tailed[path]
+	_, already := w.lookup(path)
+	stop := make(chan struct{})
 	if !already {
-		w.tailed[path] = true
+		w.tailed[path] = stop
 	}
 	w.mu.Unlock()
 	if already {
 		return
 	}
+	indexInBackground(path)
 	source := filepath.Base(path)
 	go func() {
-		tail, err := lastNLines(path, 1000)
+		tail, offsets, err := lastNLinesAt(path, 1000)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
 			return
 		}
-		for _, line := range tail {
+		for i, line := range tail {
+			select {
+			case <-stop:
+				return // removed meanwhile
+			default:
+			}
 			if line != "" {
-				w.b.publish(source, line)
+				w.b.publishAt(path, source, line, offsets[i])
 			}
 		}
-		followFile(path, source, w.b)
+		followFile(path, -1, stop, func(line string, off int64) { w.b.publishAt(path, source, line, off) })
 	}()
 }
 
@@ -116,11 +189,46 @@ func (w *Watcher) Add(path string) {
 // sorts all lines by timestamp, publishes them as a single batch,
 // then begins following each file for new lines.
 func (w *Watcher) ReopenSorted(paths []string) {
+	w.reopen(paths, func(path string) ([]string, []int64, error) { return lastNLinesAt(path, 1000) })
+}
+
+// Restore reopens the files of a session that are not already open, each from
+// its saved offset, like ReopenSorted.
+func (w *Watcher) Restore(sources []sessionSource) {
+	offsets := map[string]*int64{}
+	var paths []string
+	w.mu.Lock()
+	for _, src := range sources {
+		if _, ok := w.tailed[src.Path]; !ok {
+			offsets[src.Path] = src.Offset
+			paths = append(paths, src.Path)
+		}
+	}
+	w.mu.Unlock()
+	if len(paths) == 0 {
+		return
+	}
+	w.reopen(paths, func(path string) ([]string, []int64, error) {
+		if off := offsets[path]; off != nil {
+			return readFrom(path, *off, maxHistory)
+		}
+		return lastNLinesAt(path, 1000)
+	})
+}
+
+func (w *Watcher) reopen(paths []string, read func(path string) ([]string, []int64, error)) {
+	stops := make(map[string]chan struct{}, len(paths))
 	w.mu.Lock()
 	for _, p := range paths {
-		w.tailed[p] = true
+		if _, ok := w.tailed[p]; !ok {
+			w.tailed[p] = make(chan struct{})
+		}
+		stops[p] = w.tailed[p]
 	}
 	w.mu.Unlock()
+	for _, p := range paths {
+		indexInBackground(p)
+	}
 
 	var mu sync.Mutex
 	var all []tailLine
@@ -132,15 +240,15 @@ func (w *Watcher) ReopenSorted(paths []string) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			tail, err := lastNLines(path, 1000)
+			tail, offsets, err := read(path)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
 				return
 			}
 			local := make([]tailLine, 0, len(tail))
-			for _, line := range tail {
+			for i, line := range tail {
 				if line != "" {
-					local = append(local, tailLine{source, line, parseLineTime(line)})
+					local = append(local, tailLine{path, source, line, offsets[i], parseLineTime(line)})
 				}
 			}
 			mu.Lock()
@@ -158,15 +266,20 @@ func (w *Watcher) ReopenSorted(paths []string) {
 		return ti.Before(tj)
 	})
 
-	msgs := make([]logMsg, len(all))
+	msgs := make([]logMsg, 0, len(all))
 	for i, l := range all {
-		msgs[i] = logMsg{S: l.source, D: l.line}
+		select {
+		case <-stops[l.path]:
+			continue // removed meanwhile
+		default:
+		}
+		msgs = append(msgs, logMsg{S: l.source, D: l.line, O: &all[i].off, P: l.path})
 	}
 	w.b.publishBatch(msgs)
 
 	for _, path := range paths {
 		path := path
 		source := filepath.Base(path)
-		go followFile(path, source, w.b)
+		go followFile(path, -1, stops[path], func(line string, off int64) { w.b.publishAt(path, source, line, off) })
 	}
 }
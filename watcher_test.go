@@ -76,3 +76,64 @@ func TestWatcherReopenSortedMarksFilesAsTailed(t *testing.T) {
 	w.mu.Unlock()
 	assert.Equal(t, 2, n)
 }
+
+func TestWatcherRemoveStopsFollowing(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(p, nil, 0o644))
+	b := newBroker()
+	w := NewWatcher(b)
+	w.Add(p)
+	require.Eventually(t, func() bool {
+		require.NoError(t, appendLine(p, "before"))
+		return len(b.snapshot()) > 0
+	}, 2*time.Second, 50*time.Millisecond)
+
+	// Any spelling of the path closes it.
+	rel, err := filepath.Rel(mustGetwd(t), p)
+	require.NoError(t, err)
+	assert.True(t, w.Remove(rel))
+	assert.False(t, w.Remove(p))
+	assert.Empty(t, w.Files())
+
+	time.Sleep(300 * time.Millisecond) // let the tail goroutine notice
+	n := len(b.snapshot())
+	require.NoError(t, appendLine(p, "after"))
+	time.Sleep(300 * time.Millisecond)
+	assert.Len(t, b.snapshot(), n)
+}
+
+func TestWatcherRemoveKeepsSameBasename(t *testing.T) {
+	dir := t.TempDir()
+	a, c := filepath.Join(dir, "a", "app.log"), filepath.Join(dir, "c", "app.log")
+	for _, p := range []string{a, c} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(`{"message":"`+p+`"}`+"\n"), 0o644))
+	}
+	b := newBroker()
+	w := NewWatcher(b)
+	w.Add(a)
+	w.Add(c)
+	require.Eventually(t, func() bool { return len(b.snapshot()) == 2 }, 2*time.Second, 20*time.Millisecond)
+
+	assert.True(t, w.Remove(a))
+	hist := b.snapshot()
+	require.Len(t, hist, 1)
+	assert.Contains(t, hist[0].D, c)
+}
+
+func appendLine(path, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+func mustGetwd(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	return wd
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A workspace is a named set of files with the columns, widths and default
+// filters to show them with, e.g. "checkout" for the api and payment logs.
+// Workspaces live in configDir()/workspaces/<name>.json; `-w name` starts
+// with one and the File menu switches between them.
+type workspace struct {
+	Name         string             `json:"name"`
+	Files        []string           `json:"files"`
+	View         sessionView        `json:"view"` // level, Custom Columns and property filters
+	ColumnWidths map[string]float64 `json:"columnWidths,omitempty"`
+}
+
+var (
+	workspaceMu     sync.Mutex
+	activeWorkspace string // name of the workspace this process was started with
+)
+
+func workspacesDir() string {
+	return filepath.Join(configDir(), "workspaces")
+}
+
+// workspacePath returns the file of the workspace called name, rejecting
+// names that would escape workspacesDir.
+func workspacePath(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", errors.New("invalid workspace name")
+	}
+	return filepath.Join(workspacesDir(), name+".json"), nil
+}
+
+// listWorkspaces returns the names of all saved workspaces, sorted.
+func listWorkspaces() []string {
+	entries, err := os.ReadDir(workspacesDir())
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func loadWorkspace(name string) (workspace, error) {
+	var ws workspace
+	p, err := workspacePath(name)
+	if err != nil {
+		return ws, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return ws, err
+	}
+	err = json.Unmarshal(data, &ws)
+	ws.Name = strings.TrimSpace(name)
+	return ws, err
+}
+
+func saveWorkspace(ws workspace) error {
+	p, err := workspacePath(ws.Name)
+	if err != nil {
+		return err
+	}
+	ws.Name = strings.TrimSpace(ws.Name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+func deleteWorkspace(name string) error {
+	p, err := workspacePath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// workspaceFromView captures the open files and the UI's columns and default
+// filters. Search, timeline window and paging are left out: they belong to
+// one debugging run, not to the workspace.
+func workspaceFromView(name string, files []string, v sessionView, widths map[string]float64) workspace {
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	return workspace{
+		Name:  name,
+		Files: files,
+		View: sessionView{
+			Level:           v.Level,
+			CustomColumns:   v.CustomColumns,
+			PropertyFilters: v.PropertyFilters,
+		},
+		ColumnWidths: widths,
+	}
+}
+
+// session turns ws into a session whose view the UI picks up on load.
+func (ws workspace) session() session {
+	s := session{View: ws.View, ColumnWidths: ws.ColumnWidths}
+	for _, f := range ws.Files {
+		s.Sources = append(s.Sources, sessionSource{Path: f})
+	}
+	return s
+}
+
+func setActiveWorkspace(name string) {
+	workspaceMu.Lock()
+	activeWorkspace = name
+	workspaceMu.Unlock()
+}
+
+func currentWorkspace() string {
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	return activeWorkspace
+}
+
+// setWorkspaceView updates the columns and property filters of the workspace
+// called name, so changes made while it is open become its defaults.
+func setWorkspaceView(name string, v viewPrefs) error {
+	ws, err := loadWorkspace(name)
+	if err != nil {
+		return err
+	}
+	ws.View.CustomColumns = v.CustomColumns
+	ws.View.PropertyFilters = v.PropertyFilters
+	return saveWorkspace(ws)
+}
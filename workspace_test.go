@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceRoundTrip(t *testing.T) {
+	configDirOverride = t.TempDir()
+	t.Cleanup(func() { configDirOverride = "" })
+
+	assert.Empty(t, listWorkspaces())
+
+	view := sessionView{
+		Level:           "WARN",
+		CustomColumns:   []string{"order_id"},
+		PropertyFilters: map[string][]string{"service": {"payment"}},
+		Search:          "timeout",
+		From:            1,
+		To:              2,
+	}
+	ws := workspaceFromView("checkout", []string{"/srv/payment.log", "/srv/api.log"}, view, map[string]float64{"ts": 90})
+	require.NoError(t, saveWorkspace(ws))
+	require.NoError(t, saveWorkspace(workspace{Name: "queue", Files: []string{"/srv/worker.log"}}))
+
+	assert.Equal(t, []string{"checkout", "queue"}, listWorkspaces())
+
+	got, err := loadWorkspace("checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/srv/api.log", "/srv/payment.log"}, got.Files)
+	assert.Equal(t, sessionView{
+		Level:           "WARN",
+		CustomColumns:   []string{"order_id"},
+		PropertyFilters: map[string][]string{"service": {"payment"}},
+	}, got.View, "search and time window are not part of a workspace")
+	assert.Equal(t, map[string]float64{"ts": 90}, got.ColumnWidths)
+
+	s := got.session()
+	assert.Equal(t, []sessionSource{{Path: "/srv/api.log"}, {Path: "/srv/payment.log"}}, s.Sources)
+	assert.Equal(t, got.View, s.View)
+
+	require.NoError(t, setWorkspaceView("checkout", viewPrefs{CustomColumns: []string{"order_id", "amount"}}))
+	got, err = loadWorkspace("checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order_id", "amount"}, got.View.CustomColumns)
+	assert.Nil(t, got.View.PropertyFilters)
+	assert.Equal(t, "WARN", got.View.Level)
+
+	require.NoError(t, deleteWorkspace("queue"))
+	assert.Equal(t, []string{"checkout"}, listWorkspaces())
+	_, err = loadWorkspace("queue")
+	assert.Error(t, err)
+}
+
+func TestWorkspacePathRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"", " ", "..", "a/b", `a\b`} {
+		_, err := workspacePath(name)
+		assert.Error(t, err, "%q", name)
+	}
+	_, err := workspacePath("checkout debugging")
+	assert.NoError(t, err)
+}